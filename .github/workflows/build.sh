@@ -7,6 +7,6 @@ do
     platform_split=(${platform//\// })
     GOOS=${platform_split[0]}
     GOARCH=${platform_split[1]}
-    env GOOS=$GOOS GOARCH=$GOARCH CGO_ENABLED=0 go build -ldflags "-s -w -X github.com/arken/ark/manifest/upstream.GitHubClientID=$1 -X github.com/arken/ark/config.Version=$2" -o ark-$2-${GOOS}-${GOARCH} .
+    env GOOS=$GOOS GOARCH=$GOARCH CGO_ENABLED=0 go build -ldflags "-s -w -X github.com/arken/ark/manifest/upstream.GitHubClientID=$1 -X github.com/arken/ark/manifest/upstream.GitLabClientID=$3 -X github.com/arken/ark/config.Version=$2" -o ark-$2-${GOOS}-${GOARCH} .
 
 done
@@ -1,14 +1,14 @@
 package cli
 
 import (
-	"bufio"
 	"fmt"
 	"os"
 	"path/filepath"
-	"sort"
-	"strings"
 
 	"github.com/DataDrake/cli-ng/v2/cmd"
+	"github.com/arken/ark/ipfs"
+	"github.com/arken/ark/stage"
+	"github.com/schollz/progressbar/v3"
 )
 
 func init() {
@@ -21,6 +21,7 @@ var Add = cmd.Sub{
 	Alias: "ad",
 	Short: "Stage a file for set of files for a submission.",
 	Args:  &AddArgs{},
+	Flags: &AddFlags{},
 	Run:   AddRun,
 }
 
@@ -29,6 +30,11 @@ type AddArgs struct {
 	Paths []string
 }
 
+// AddFlags handles the specific flags for the add command.
+type AddFlags struct {
+	Force bool `short:"f" long:"force" desc:"Add files even if they match an ignore pattern."`
+}
+
 // AddRun stages a file within the current working directory for a later submission.
 func AddRun(r *cmd.Root, c *cmd.Sub) {
 	// Setup main application config.
@@ -47,57 +53,129 @@ func AddRun(r *cmd.Root, c *cmd.Sub) {
 		os.Exit(1)
 	}
 
-	// Initialize file cache and paths from args
-	fileCache := make(map[string]bool)
+	// Initialize paths from args
 	argPaths := c.Args.(*AddArgs).Paths
+	flags := c.Flags.(*AddFlags)
+	ignores := newIgnoreList(flags.Force)
 
-	// Open previous cache if exists
-	f, err := os.Open(AddedFilesPath)
-	if err == nil {
-		// Import existing cache from file.
-		scanner := bufio.NewScanner(f)
-		for scanner.Scan() {
-			if len(scanner.Text()) > 0 {
-				fileCache[scanner.Text()] = true
-			}
-		}
-		f.Close()
-	}
+	// Open the staging index.
+	index, err := stage.Read()
+	checkError(rFlags, err)
 
 	// Iterate through paths to check they exist
 	// if adding a dir add all sub files within that dir.
+	numAdded, numFailed := 0, 0
+	staged := []string{}
 	for _, path := range argPaths {
-		stat, err := os.Stat(path)
-		checkError(rFlags, err)
-
-		// Walk through a directory and add all children files.
-		if stat.IsDir() {
-			filepath.Walk(path, func(path string, info os.FileInfo, err error) error {
-				if !info.IsDir() {
-					fileCache[path] = true
-				}
-				return nil
-			})
+		var paths []string
+
+		// Expand glob patterns without relying on the shell.
+		if isGlob(path) {
+			paths, err = expandGlob(path, ignores)
+			checkError(rFlags, err)
+			if len(paths) == 0 {
+				fmt.Printf("No files match %v\n", path)
+			}
 		} else {
-			fileCache[path] = true
+			stat, err := os.Stat(path)
+			if err != nil {
+				fmt.Println(err)
+				numFailed++
+				continue
+			}
+
+			// Walk through a directory and add all children files.
+			if stat.IsDir() {
+				paths, err = walkFiles(path, ignores, nil)
+				checkError(rFlags, err)
+			} else {
+				ignored, err := ignores.Ignored(path, false)
+				checkError(rFlags, err)
+				if ignored {
+					fmt.Printf("Skipping %v because it matches an ignore pattern, use --force to add it.\n", path)
+					continue
+				}
+				paths = []string{filepath.Clean(path)}
+			}
 		}
-	}
 
-	// Create a string of the keys of the cache map.
-	keys := make([]string, 0, len(fileCache))
-	for k := range fileCache {
-		keys = append(keys, k)
+		for _, path := range paths {
+			info, err := os.Stat(path)
+			checkError(rFlags, err)
+			if index.Add(path, info) {
+				numAdded++
+			}
+			staged = append(staged, path)
+		}
 	}
 
-	// Sort output cache for readability
-	sort.Strings(keys)
+	// Write out the index before hashing so nothing is lost on failure.
+	err = index.Write()
+	checkError(rFlags, err)
+
+	// Hash new and modified files so later commands can reuse their CIDs.
+	node, err := ipfs.CreateOfflineNode()
+	checkError(rFlags, err)
 
-	f, err = os.Create(AddedFilesPath)
+	err = hashStaged(node, index, ".", staged)
+	if err != nil {
+		index.Write()
+	}
 	checkError(rFlags, err)
-	defer f.Close()
 
-	// Write out cache to file.
-	_, err = f.WriteString(strings.Join(keys, "\n") + "\n")
+	err = index.Write()
 	checkError(rFlags, err)
 
+	fmt.Printf("Added %d file(s), %d file(s) now staged for submission.\n", numAdded, index.Len())
+	if numFailed > 0 {
+		fmt.Printf("%d path(s) could not be added.\n", numFailed)
+		os.Exit(1)
+	}
+}
+
+// hashStaged computes the CIDs and types of the staged paths that haven't
+// been hashed since they last changed, reading the files from within dir.
+func hashStaged(node *ipfs.Node, index *stage.Index, dir string, paths []string) error {
+	// Find files without a CID or that changed since they were hashed.
+	pending := []string{}
+	seen := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		index.Add(path, info)
+		entry := index.Entries[path]
+		if (entry.CID == "" || entry.Type == "") && !seen[path] {
+			pending = append(pending, path)
+		}
+		seen[path] = true
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	// Generate progress bar
+	bar := progressbar.Default(int64(len(pending)), "Hashing files")
+	bar.RenderBlank()
+
+	for _, path := range pending {
+		entry := index.Entries[path]
+		if entry.CID == "" {
+			cid, err := node.Hash(filepath.Join(dir, path))
+			if err != nil {
+				return err
+			}
+			entry.CID = cid
+		}
+		if entry.Type == "" {
+			mimeType, err := stage.DetectType(filepath.Join(dir, path))
+			if err != nil {
+				return err
+			}
+			entry.Type = mimeType
+		}
+		bar.Add(1)
+	}
+	return nil
 }
@@ -0,0 +1,282 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/DataDrake/cli-ng/v2/cmd"
+	"github.com/arken/ark/config"
+	"github.com/arken/ark/credential"
+	"github.com/arken/ark/manifest"
+	"github.com/arken/ark/manifest/upstream"
+)
+
+func init() {
+	cmd.Register(&Auth)
+}
+
+// Auth manages the access tokens Ark uses to submit to manifests.
+var Auth = cmd.Sub{
+	Name:  "auth",
+	Alias: "au",
+	Short: "Log in to, out of, or check the status of a manifest's host.",
+	Args:  &AuthArgs{},
+	Run:   AuthRun,
+}
+
+// AuthArgs handles the specific arguments for the auth command.
+type AuthArgs struct {
+	Action  string
+	Targets []string `zero:"true"`
+}
+
+// AuthRun runs an auth action: "login" or "logout" of the host of a
+// manifest, or "status" to check the tokens Ark has for each host.
+func AuthRun(r *cmd.Root, c *cmd.Sub) {
+	// Setup main application config.
+	rFlags := rootInit(r)
+
+	args := c.Args.(*AuthArgs)
+
+	switch args.Action {
+	case "login":
+		if len(args.Targets) != 1 {
+			checkErrorCode(rFlags, exitUsage, fmt.Errorf("usage: ark auth login <manifest|host>"))
+		}
+		location := authLocation(args.Targets[0])
+		host := credentialHost(location)
+		if host == "" || !manifest.NeedsToken(location) {
+			checkErrorCode(rFlags, exitUsage, fmt.Errorf("%s doesn't use an access token", args.Targets[0]))
+		}
+
+		cred := login(rFlags, location)
+		err := credentialStore(rFlags).Store(cred)
+		checkError(rFlags, err)
+		fmt.Printf("Logged in to %s as %s.\n", cred.Host, cred.Username)
+	case "logout":
+		if len(args.Targets) != 1 {
+			checkErrorCode(rFlags, exitUsage, fmt.Errorf("usage: ark auth logout <manifest|host>"))
+		}
+		host := credentialHost(authLocation(args.Targets[0]))
+		err := credentialStore(rFlags).Erase(host)
+		if err == credential.ErrNotFound {
+			err = fmt.Errorf("not logged in to %s", host)
+		}
+		checkError(rFlags, err)
+		fmt.Printf("Logged out of %s.\n", host)
+	case "status":
+		hosts := []string{}
+		for _, target := range args.Targets {
+			hosts = append(hosts, credentialHost(authLocation(target)))
+		}
+		if len(hosts) == 0 {
+			hosts = knownHosts()
+		}
+
+		// Checking the status shouldn't make a helper ask to log in.
+		store := credentialStore(rFlags)
+		if helper, ok := store.(*credential.HelperStore); ok {
+			helper.NoPrompt = true
+		}
+		found, invalid := 0, 0
+		for _, host := range hosts {
+			cred, err := store.Get(host)
+			if err == credential.ErrNotFound {
+				if len(args.Targets) > 0 {
+					fmt.Printf("%s: not logged in\n", host)
+				}
+				continue
+			}
+			checkError(rFlags, err)
+			found++
+
+			// Check the token is still valid with the host.
+			info, err := manifest.CheckToken("https://"+host, cred.Token)
+			switch {
+			case err != nil && err.Error() == "unknown upstream":
+				fmt.Printf("%s: logged in as %s (unverified)\n", host, cred.Username)
+			case err != nil:
+				fmt.Printf("%s: token for %s is invalid: %v\n", host, cred.Username, err)
+				invalid++
+			case len(info.Scopes) > 0:
+				fmt.Printf("%s: logged in as %s (scopes: %s)\n", host, info.User, strings.Join(info.Scopes, ", "))
+			default:
+				fmt.Printf("%s: logged in as %s\n", host, info.User)
+			}
+		}
+		if found == 0 && len(args.Targets) == 0 {
+			fmt.Println("Not logged in to any hosts.")
+		}
+		if config.Global.Git.Token != "" {
+			fmt.Println("A token set with git.token or ARK_GIT_TOKEN is used for every host.")
+		}
+		if configToken(rFlags) != "" {
+			fmt.Printf("Warning: %s stores a git token in plain text. It will be moved\n", rFlags.Config)
+			fmt.Println("to the credential store the next time it's used to submit, or you")
+			fmt.Println("can remove it with \"ark config git.token ''\" and log in again.")
+		}
+		if invalid > 0 {
+			fmt.Println("Use \"ark auth login <host>\" to replace invalid tokens.")
+			os.Exit(exitAuth)
+		}
+	default:
+		checkErrorCode(rFlags, exitUsage, fmt.Errorf("unknown auth action %q, expected login, logout, or status", args.Action))
+	}
+}
+
+// login walks the user through the auth flow of the upstream for
+// a manifest's host and returns the credential it provides.
+func login(rFlags *GlobalFlags, location string) credential.Credential {
+	// Give the user a chance to change the account they logged in with
+	// if it was incorrect.
+	correctUser := false
+	var guard upstream.Guard
+	var username string
+	var err error
+	for !correctUser {
+		guard, err = manifest.Auth(location)
+		if err != nil && err.Error() == "unknown upstream" {
+			fmt.Println("Error: Ark was unable to identify a known upstream")
+			fmt.Println("for your repository. Please add its host to the")
+			fmt.Println("[upstreams] of your config, or set ARK_GIT_TOKEN")
+			fmt.Println("before retrying.")
+		}
+		checkError(rFlags, err)
+
+		if tokenGuard, ok := guard.(upstream.TokenGuard); ok {
+			// Ask for a token the user creates themselves.
+			tokenGuard.SetAccessToken(queryUserToken(guard.GetVerificationURL()))
+			_, err = guard.CheckStatus()
+			checkError(rFlags, err)
+		} else {
+			// Print out message to user about device code.
+			printAuthCode(guard.GetVerificationURL(), guard.GetCode(), guard.GetExpireInterval())
+
+			// Begin polling process for authorization
+			interval := time.Duration(guard.GetInterval()) * time.Second
+			for {
+				wait(interval)
+				status, err := guard.CheckStatus()
+				checkError(rFlags, err)
+
+				if status == "slow_down" {
+					interval = interval + 5*time.Second
+					continue
+				}
+				if status != "authorization_pending" {
+					break
+				}
+			}
+			fmt.Print("\r")
+		}
+		username, err = guard.GetUser()
+		checkError(rFlags, err)
+
+		correctUser = queryUserCorrect(username)
+		fmt.Println()
+	}
+
+	return credential.Credential{
+		Host:     credentialHost(location),
+		Username: username,
+		Token:    guard.GetAccessToken(),
+	}
+}
+
+// credentialStore opens the store configured for access tokens, which
+// defaults to a file alongside the config.
+func credentialStore(rFlags *GlobalFlags) credential.Store {
+	return credential.Open(
+		config.Global.Credentials.Helper,
+		filepath.Join(filepath.Dir(rFlags.Config), "credentials.toml"),
+	)
+}
+
+// configToken returns the token saved in plain text in the config
+// file by older versions of Ark, ignoring any set in the environment.
+func configToken(rFlags *GlobalFlags) string {
+	onDisk := config.Config{}
+	err := config.ParseFile(rFlags.Config, &onDisk)
+	if err != nil {
+		return ""
+	}
+	return onDisk.Git.Token
+}
+
+// migrateToken moves a token saved in plain text in the config file by
+// older versions of Ark into the credential store. The token was used
+// for every host, so it's only moved once the host of location has
+// accepted it for user.
+func migrateToken(rFlags *GlobalFlags, location, user string) error {
+	onDisk := config.Config{}
+	err := config.ParseFile(rFlags.Config, &onDisk)
+	if err != nil || onDisk.Git.Token == "" || onDisk.Git.Token != config.Global.Git.Token {
+		return nil
+	}
+
+	cred := credential.Credential{
+		Host:     credentialHost(location),
+		Username: user,
+		Token:    onDisk.Git.Token,
+	}
+	err = credentialStore(rFlags).Store(cred)
+	if err != nil {
+		return err
+	}
+
+	onDisk.Git.Token = ""
+	err = config.WriteFile(rFlags.Config, &onDisk)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Moved the git token in %s to the credential store for %s.\n", rFlags.Config, cred.Host)
+	return nil
+}
+
+// authLocation converts an alias or a bare host, such as
+// "github.com", into the location of a repository.
+func authLocation(target string) string {
+	if alias, ok := config.Global.Manifest.Aliases[target]; ok {
+		return alias
+	}
+	if !strings.Contains(target, "/") {
+		return "https://" + target
+	}
+	return target
+}
+
+// credentialHost returns the host a manifest's credentials are stored
+// for, which is empty for manifests that aren't hosted remotely.
+func credentialHost(location string) string {
+	if alias, ok := config.Global.Manifest.Aliases[location]; ok {
+		location = alias
+	}
+	u, err := manifest.ParseLocation(location)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+// knownHosts lists the hosts of Ark's upstreams and manifest aliases.
+func knownHosts() []string {
+	seen := map[string]bool{}
+	for host := range upstream.AvailableUpstreams {
+		seen[host] = true
+	}
+	for _, alias := range config.Global.Manifest.Aliases {
+		seen[credentialHost(alias)] = true
+	}
+	delete(seen, "")
+
+	hosts := make([]string, 0, len(seen))
+	for host := range seen {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	return hosts
+}
@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/arken/ark/config"
+	"github.com/arken/ark/credential"
+)
+
+func TestMigrateToken(t *testing.T) {
+	dir := t.TempDir()
+	rFlags := &GlobalFlags{Config: filepath.Join(dir, "config.toml")}
+	// Read the token from the file rather than the environment.
+	t.Setenv("ARK_GIT_TOKEN", "")
+	os.Unsetenv("ARK_GIT_TOKEN")
+	t.Cleanup(func() { config.Global = config.Config{} })
+	err := os.WriteFile(rFlags.Config, []byte("[git]\nname = \"Bob\"\ntoken = \"secret\"\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = config.Init(rFlags.Config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if configToken(rFlags) != "secret" {
+		t.Fatal("the token in the config file wasn't found")
+	}
+
+	err = migrateToken(rFlags, "https://github.com/arken/core-manifest", "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The token is only stored for the host that accepted it.
+	cred, err := credential.NewFileStore(filepath.Join(dir, "credentials.toml")).Get("github.com")
+	want := credential.Credential{Host: "github.com", Username: "bob", Token: "secret"}
+	if err != nil || cred != want {
+		t.Errorf("stored credential = %+v, %v, want %+v", cred, err, want)
+	}
+	if token := configToken(rFlags); token != "" {
+		t.Errorf("the config file still has the token %q", token)
+	}
+	onDisk := config.Config{}
+	err = config.ParseFile(rFlags.Config, &onDisk)
+	if err != nil || onDisk.Git.Name != "Bob" {
+		t.Errorf("the rest of the config wasn't kept: %+v, %v", onDisk.Git, err)
+	}
+
+	// The token is still used for the rest of the command.
+	if config.Global.Git.Token != "secret" {
+		t.Error("the token was removed from the running config")
+	}
+}
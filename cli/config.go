@@ -49,13 +49,17 @@ func ConfigRun(r *cmd.Root, c *cmd.Sub) {
 
 	// Use reflect to get/set the value of the config struct
 	reConf := reflect.ValueOf(&cfg).Elem().FieldByName(category)
+	reField := reConf.FieldByName(field)
+	if !reField.IsValid() {
+		reField = fieldByTag(reConf, confKeys[1])
+	}
 	if len(args.Value) > 0 {
-		reConf.FieldByName(field).SetString(args.Value[0])
+		reField.SetString(args.Value[0])
 		// Write changes back to config file.
 		config.WriteFile(rFlags.Config, &cfg)
 	} else {
 		// Print current value if no new value given.
-		fmt.Println(reConf.FieldByName(field))
+		fmt.Println(reField)
 	}
 }
 
@@ -64,3 +68,15 @@ func ConfigRun(r *cmd.Root, c *cmd.Sub) {
 func capName(in string) string {
 	return strings.ToUpper(string(in[0])) + in[1:]
 }
+
+// fieldByTag returns the field of a config struct whose toml
+// key matches name, such as "ssh_key" for SSHKey.
+func fieldByTag(v reflect.Value, name string) reflect.Value {
+	for i := 0; i < v.NumField(); i++ {
+		tag := strings.Split(v.Type().Field(i).Tag.Get("toml"), ",")[0]
+		if tag == name {
+			return v.Field(i)
+		}
+	}
+	return reflect.Value{}
+}
@@ -0,0 +1,129 @@
+package cli
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// isGlob reports whether a path contains any glob meta characters.
+func isGlob(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?[")
+}
+
+// globBase returns the longest leading directory of a glob pattern
+// that doesn't contain any meta characters.
+func globBase(pattern string) string {
+	components := strings.Split(filepath.ToSlash(filepath.Clean(pattern)), "/")
+	base := []string{}
+	for _, component := range components[:len(components)-1] {
+		if isGlob(component) {
+			break
+		}
+		base = append(base, component)
+	}
+	if len(base) == 0 {
+		return "."
+	}
+	if base[0] == "" {
+		return "/" + filepath.Join(base[1:]...)
+	}
+	return filepath.Join(base...)
+}
+
+// matchGlob reports whether a path matches a glob pattern where "**"
+// matches any number of directories, including none.
+func matchGlob(pattern, name string) bool {
+	return matchComponents(
+		strings.Split(filepath.ToSlash(filepath.Clean(pattern)), "/"),
+		strings.Split(filepath.ToSlash(filepath.Clean(name)), "/"),
+	)
+}
+
+// matchGlobOrParent reports whether a path or any of its parent
+// directories match a glob pattern.
+func matchGlobOrParent(pattern, name string) bool {
+	for name != "." && name != "/" {
+		if matchGlob(pattern, name) {
+			return true
+		}
+		name = filepath.Dir(name)
+	}
+	return false
+}
+
+// inDirectory reports whether a cleaned path is within a cleaned
+// directory, where "." contains every relative path.
+func inDirectory(dir, name string) bool {
+	return dir == "." || strings.HasPrefix(name, dir+string(filepath.Separator))
+}
+
+// matchComponents matches the components of a path against the
+// components of a glob pattern.
+func matchComponents(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// Collapse repeated "**" and try every possible split.
+			for len(pattern) > 0 && pattern[0] == "**" {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 0 {
+				return true
+			}
+			for i := range name {
+				if matchComponents(pattern, name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if matched, err := path.Match(pattern[0], name[0]); err != nil || !matched {
+			return false
+		}
+		pattern = pattern[1:]
+		name = name[1:]
+	}
+	return len(name) == 0
+}
+
+// expandGlob walks the filesystem beneath the base of a glob pattern and
+// returns every file that matches it. Matching directories contribute all
+// of the files within them.
+func expandGlob(pattern string, ignores *ignoreList) ([]string, error) {
+	result, err := walkFiles(globBase(pattern), ignores, func(path string) bool {
+		return matchGlobOrParent(pattern, path)
+	})
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return result, err
+}
+
+// walkFiles returns the cleaned paths of all files beneath root that
+// aren't ignored and, if match isn't nil, that match reports true for.
+func walkFiles(root string, ignores *ignoreList, match func(path string) bool) (result []string, err error) {
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		ignored, err := ignores.Ignored(path, info.IsDir())
+		if err != nil {
+			return err
+		}
+		if ignored {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !info.IsDir() && (match == nil || match(path)) {
+			result = append(result, filepath.Clean(path))
+		}
+		return nil
+	})
+	return result, err
+}
@@ -0,0 +1,164 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGlobBase(t *testing.T) {
+	tests := map[string]string{
+		"*.txt":             ".",
+		"docs/*.txt":        "docs",
+		"docs/a/**/*.txt":   filepath.Join("docs", "a"),
+		"docs/*/a/*.txt":    "docs",
+		"/srv/data/**":      "/" + filepath.Join("srv", "data"),
+		"docs/notes.txt":    "docs",
+		"./docs/[ab].txt":   "docs",
+		"docs/../img/*.png": "img",
+	}
+	for pattern, want := range tests {
+		if got := globBase(pattern); got != want {
+			t.Errorf("globBase(%q) = %q, want %q", pattern, got, want)
+		}
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"*.txt", "a.txt", true},
+		{"*.txt", "docs/a.txt", false},
+		{"docs/*.txt", "docs/a.txt", true},
+		{"docs/?.txt", "docs/ab.txt", false},
+		{"docs/[ab].txt", "docs/b.txt", true},
+		{"**/*.txt", "a.txt", true},
+		{"**/*.txt", "docs/a/b/c.txt", true},
+		{"**/*.txt", "docs/a/b/c.png", false},
+		{"docs/**/c.txt", "docs/c.txt", true},
+		{"docs/**/c.txt", "docs/a/b/c.txt", true},
+		{"docs/**/c.txt", "img/a/c.txt", false},
+		{"docs/**", "docs/a/b.txt", true},
+		{"docs/**/**/b.txt", "docs/a/b.txt", true},
+		{"docs/**/a/*.txt", "docs/x/a/y/b.txt", false},
+		{"./docs/*.txt", "docs/a.txt", true},
+		{"docs/*", "docs", false},
+		{"[", "[", false},
+	}
+	for _, test := range tests {
+		if got := matchGlob(test.pattern, test.name); got != test.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", test.pattern, test.name, got, test.want)
+		}
+	}
+}
+
+func TestMatchGlobOrParent(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"docs/*", "docs/a/b.txt", true},
+		{"d*", "docs/a/b.txt", true},
+		{"*/a", "docs/a/b.txt", true},
+		{"*/b", "docs/a/b.txt", false},
+		{"*.txt", "docs/a.txt", false},
+		{"**/a", "docs/x/a/b.txt", true},
+	}
+	for _, test := range tests {
+		if got := matchGlobOrParent(test.pattern, test.name); got != test.want {
+			t.Errorf("matchGlobOrParent(%q, %q) = %v, want %v", test.pattern, test.name, got, test.want)
+		}
+	}
+}
+
+func TestInDirectory(t *testing.T) {
+	sep := string(filepath.Separator)
+	tests := []struct {
+		dir, name string
+		want      bool
+	}{
+		{".", "a.txt", true},
+		{"docs", "docs" + sep + "a.txt", true},
+		{"docs", "docs" + sep + "a" + sep + "b.txt", true},
+		{"docs", "docs", false},
+		{"docs", "docs2" + sep + "a.txt", false},
+		{"docs" + sep + "a", "docs" + sep + "b.txt", false},
+	}
+	for _, test := range tests {
+		if got := inDirectory(test.dir, test.name); got != test.want {
+			t.Errorf("inDirectory(%q, %q) = %v, want %v", test.dir, test.name, got, test.want)
+		}
+	}
+}
+
+// chdirTemp changes into a new temporary directory for the
+// rest of a test.
+func chdirTemp(t *testing.T) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Chdir(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+func TestExpandGlob(t *testing.T) {
+	chdirTemp(t)
+	files := []string{
+		"a.txt",
+		"docs/b.txt",
+		"docs/c.png",
+		"docs/deep/d.txt",
+		"docs/skip/e.txt",
+		"img/f.png",
+		".ark/index.json",
+	}
+	for _, file := range files {
+		path := filepath.FromSlash(file)
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(path, nil, 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := os.WriteFile(IgnoreFileName, []byte("docs/skip/\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		pattern string
+		force   bool
+		want    []string
+	}{
+		{"**/*.txt", false, []string{"a.txt", "docs/b.txt", "docs/deep/d.txt"}},
+		{"**/*.txt", true, []string{"a.txt", "docs/b.txt", "docs/deep/d.txt", "docs/skip/e.txt"}},
+		{"docs/*", false, []string{"docs/b.txt", "docs/c.png", "docs/deep/d.txt"}},
+		{"*/*.png", false, []string{"docs/c.png", "img/f.png"}},
+		{"missing/*.txt", false, nil},
+	}
+	for _, test := range tests {
+		got, err := expandGlob(test.pattern, newIgnoreList(test.force))
+		if err != nil {
+			t.Errorf("expandGlob(%q) error = %v", test.pattern, err)
+			continue
+		}
+		var want []string
+		for _, file := range test.want {
+			want = append(want, filepath.FromSlash(file))
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("expandGlob(%q, force %v) = %v, want %v", test.pattern, test.force, got, want)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+)
+
+// IgnoreFileName is the name of the files holding gitignore
+// style patterns for paths Ark should never stage.
+const IgnoreFileName string = ".arkignore"
+
+// defaultIgnores are ignored even without an .arkignore file.
+var defaultIgnores = []string{
+	".ark/",
+	".git/",
+	".hg/",
+	".svn/",
+	".bzr/",
+	IgnoreFileName,
+	pullStateFile,
+	"*" + pullTempSuffix,
+}
+
+// ignoreList lazily loads the .arkignore files found between the
+// root of an Ark repository and the paths it's asked about.
+type ignoreList struct {
+	force    bool
+	patterns []gitignore.Pattern
+	loaded   map[string]bool
+}
+
+// newIgnoreList creates an ignoreList for the Ark repository in the
+// current working directory. A forced list never ignores anything.
+func newIgnoreList(force bool) *ignoreList {
+	result := &ignoreList{
+		force:  force,
+		loaded: make(map[string]bool),
+	}
+	for _, pattern := range defaultIgnores {
+		result.patterns = append(result.patterns, gitignore.ParsePattern(pattern, nil))
+	}
+	return result
+}
+
+// Ignored reports whether a path relative to the root of the Ark
+// repository matches any of the loaded ignore patterns.
+func (l *ignoreList) Ignored(path string, isDir bool) (bool, error) {
+	if l.force {
+		return false, nil
+	}
+
+	components := splitPath(path)
+	if len(components) == 0 {
+		return false, nil
+	}
+
+	// Load any .arkignore files from the root down to the path's parent
+	// so that deeper files take priority over shallower ones.
+	for i := 0; i < len(components); i++ {
+		err := l.load(components[:i])
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return gitignore.NewMatcher(l.patterns).Match(components, isDir), nil
+}
+
+// load reads the .arkignore file within a directory if it
+// hasn't been read already.
+func (l *ignoreList) load(domain []string) error {
+	dir := filepath.Join(append([]string{"."}, domain...)...)
+	if l.loaded[dir] {
+		return nil
+	}
+	l.loaded[dir] = true
+
+	f, err := os.Open(filepath.Join(dir, IgnoreFileName))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") || len(strings.TrimSpace(line)) == 0 {
+			continue
+		}
+		l.patterns = append(l.patterns, gitignore.ParsePattern(line, domain))
+	}
+	return scanner.Err()
+}
+
+// splitPath breaks a path into its components relative to
+// the current working directory.
+func splitPath(path string) []string {
+	if filepath.IsAbs(path) {
+		wd, err := os.Getwd()
+		if err == nil {
+			rel, err := filepath.Rel(wd, path)
+			if err == nil {
+				path = rel
+			}
+		}
+	}
+
+	path = filepath.ToSlash(filepath.Clean(path))
+	if path == "." {
+		return nil
+	}
+	return strings.Split(path, "/")
+}
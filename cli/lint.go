@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/DataDrake/cli-ng/v2/cmd"
+	"github.com/arken/ark/manifest"
+)
+
+func init() {
+	cmd.Register(&Lint)
+}
+
+// Lint validates the configuration and keysets of a manifest repository.
+var Lint = cmd.Sub{
+	Name:  "lint",
+	Alias: "ln",
+	Short: "Check a manifest repository for invalid configuration and keysets.",
+	Args:  &LintArgs{},
+	Flags: &LintFlags{},
+	Run:   LintRun,
+}
+
+// LintArgs handles the specific arguments for the lint command.
+type LintArgs struct {
+	Manifest string
+}
+
+// LintFlags handles the specific flags for the lint command.
+type LintFlags struct {
+	JSON bool `short:"j" long:"json" desc:"Print the results as JSON for use in automated checks."`
+}
+
+// lintResult is the JSON output of the lint command.
+type lintResult struct {
+	Manifest string             `json:"manifest"`
+	Problems []manifest.Problem `json:"problems"`
+}
+
+// LintRun lints a manifest repository, either a local checkout or one
+// cloned from a URL or alias, and exits with a failure if any errors
+// are found. Warnings are reported without failing.
+func LintRun(r *cmd.Root, c *cmd.Sub) {
+	// Setup main application config.
+	rFlags := rootInit(r)
+
+	args := c.Args.(*LintArgs)
+	flags := c.Flags.(*LintFlags)
+
+	// Lint a local checkout in place, otherwise clone the manifest.
+	path := args.Manifest
+	info, err := os.Stat(path)
+	if err != nil || !info.IsDir() {
+		m, manifestPath, err := openManifest(args.Manifest, gitOptions(rFlags, args.Manifest, false))
+		if m == nil {
+			checkError(rFlags, err)
+		}
+		path = filepath.Join(manifestPath, "manifest")
+	}
+
+	problems, err := manifest.Lint(path)
+	checkError(rFlags, err)
+
+	errors := 0
+	for _, problem := range problems {
+		if problem.Severity == manifest.SeverityError {
+			errors++
+		}
+	}
+
+	if flags.JSON {
+		if problems == nil {
+			problems = []manifest.Problem{}
+		}
+		out, err := json.MarshalIndent(lintResult{Manifest: args.Manifest, Problems: problems}, "", "  ")
+		checkError(rFlags, err)
+		fmt.Println(string(out))
+	} else {
+		for _, problem := range problems {
+			fmt.Println(problem)
+		}
+		fmt.Printf("%d error(s) and %d warning(s) found\n", errors, len(problems)-errors)
+	}
+
+	if errors > 0 {
+		os.Exit(exitFailure)
+	}
+}
@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/DataDrake/cli-ng/v2/cmd"
+	"github.com/arken/ark/manifest"
+)
+
+func init() {
+	cmd.Register(&Ls)
+}
+
+// Ls browses the category tree of a manifest.
+var Ls = cmd.Sub{
+	Name:  "ls",
+	Alias: "l",
+	Short: "List the categories and keysets within a manifest.",
+	Args:  &LsArgs{},
+	Flags: &LsFlags{},
+	Run:   LsRun,
+}
+
+// LsArgs handles the specific arguments for the ls command.
+type LsArgs struct {
+	Manifest string
+	Category []string `zero:"true"`
+}
+
+// LsFlags handles the specific flags for the ls command.
+type LsFlags struct {
+	Tree bool `short:"t" long:"tree" desc:"List every category and keyset beneath the category."`
+}
+
+// LsRun prints the categories and keysets within a category of a manifest.
+func LsRun(r *cmd.Root, c *cmd.Sub) {
+	// Setup main application config.
+	rFlags := rootInit(r)
+
+	args := c.Args.(*LsArgs)
+	flags := c.Flags.(*LsFlags)
+
+	category := "."
+	if len(args.Category) > 0 {
+		category = args.Category[0]
+	}
+
+	// Initialize Manifest
+	m, _, err := openManifest(args.Manifest, gitOptions(rFlags, args.Manifest, false))
+	checkError(rFlags, err)
+
+	err = printCategory(m, category, "", flags.Tree)
+	checkError(rFlags, err)
+}
+
+// printCategory prints the contents of a category, recursing into
+// sub-categories when printing a tree.
+func printCategory(m *manifest.Manifest, category, indent string, tree bool) error {
+	nodes, err := m.List(category)
+	if err != nil {
+		return err
+	}
+
+	for _, node := range nodes {
+		if !node.IsKeyset {
+			fmt.Printf("%s%s/\n", indent, node.Name)
+			if tree {
+				err = printCategory(m, filepath.Join(category, node.Name), indent+"    ", tree)
+				if err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		fmt.Printf("%s%s (%d entries)\n", indent, node.Name, node.Entries)
+	}
+	return nil
+}
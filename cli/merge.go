@@ -0,0 +1,63 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/DataDrake/cli-ng/v2/cmd"
+	"github.com/arken/ark/manifest"
+)
+
+func init() {
+	cmd.Register(&Merge)
+}
+
+// Merge merges a pending submission into a manifest.
+var Merge = cmd.Sub{
+	Name:  "merge",
+	Alias: "mg",
+	Short: "Merge a pending submission into a manifest.",
+	Args:  &MergeArgs{},
+	Flags: &MergeFlags{},
+	Run:   MergeRun,
+}
+
+// MergeArgs handles the specific arguments for the merge command.
+type MergeArgs struct {
+	Manifest string
+	Branch   string
+}
+
+// MergeFlags handles the specific flags for the merge command.
+type MergeFlags struct {
+	Yes bool `short:"y" long:"yes" desc:"Never prompt for input, fail with a non-zero exit code instead."`
+}
+
+// MergeRun merges the submission on a branch listed by "ark pending" into
+// the manifest and removes it from the pending submissions.
+func MergeRun(r *cmd.Root, c *cmd.Sub) {
+	// Setup main application config.
+	rFlags := rootInit(r)
+
+	args := c.Args.(*MergeArgs)
+	flags := c.Flags.(*MergeFlags)
+
+	checkGitIdentity(rFlags, !flags.Yes)
+
+	// Initialize Manifest
+	m, _, err := openManifest(args.Manifest, gitOptions(rFlags, args.Manifest, true))
+	checkError(rFlags, err)
+
+	err = m.Merge(args.Branch)
+	conflict := &manifest.ConflictError{}
+	switch {
+	case errors.As(err, &conflict):
+		fmt.Println("Ask the submitter to update their submission before merging it.")
+		checkErrorCode(rFlags, exitConflict, err)
+	case err == manifest.ErrNoSubmissions || err == manifest.ErrSubmissionNotFound:
+		checkErrorCode(rFlags, exitUsage, err)
+	}
+	checkError(rFlags, err)
+
+	fmt.Printf("Merged %s into %s.\n", args.Branch, args.Manifest)
+}
@@ -0,0 +1,58 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/DataDrake/cli-ng/v2/cmd"
+	"github.com/arken/ark/manifest"
+)
+
+func init() {
+	cmd.Register(&Pending)
+}
+
+// Pending lists the submissions waiting to be merged into a manifest.
+var Pending = cmd.Sub{
+	Name:  "pending",
+	Alias: "pd",
+	Short: "List the submissions waiting to be merged into a manifest.",
+	Args:  &PendingArgs{},
+	Run:   PendingRun,
+}
+
+// PendingArgs handles the specific arguments for the pending command.
+type PendingArgs struct {
+	Manifest string
+}
+
+// PendingRun prints the pending submissions to a manifest hosted on a
+// plain git server, which maintainers can merge with "ark merge".
+func PendingRun(r *cmd.Root, c *cmd.Sub) {
+	// Setup main application config.
+	rFlags := rootInit(r)
+
+	args := c.Args.(*PendingArgs)
+
+	// Initialize Manifest
+	m, _, err := openManifest(args.Manifest, gitOptions(rFlags, args.Manifest, false))
+	checkError(rFlags, err)
+
+	submissions, err := m.Submissions()
+	if err == manifest.ErrNoSubmissions {
+		checkErrorCode(rFlags, exitUsage, err)
+	}
+	checkError(rFlags, err)
+
+	if len(submissions) == 0 {
+		fmt.Println("No pending submissions.")
+		return
+	}
+	for _, submission := range submissions {
+		fmt.Printf("%s\t%s\n", submission.Branch, submission.Title)
+		if submission.Author != "" {
+			fmt.Printf("\tby %s on %s\n", submission.Author, submission.Created.Local().Format("2006-01-02 15:04"))
+		} else {
+			fmt.Printf("\ton %s\n", submission.Created.Local().Format("2006-01-02 15:04"))
+		}
+	}
+}
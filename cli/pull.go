@@ -2,19 +2,20 @@ package cli
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
-	"net/url"
+	"io"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/DataDrake/cli-ng/v2/cmd"
-	"github.com/arken/ark/config"
 	"github.com/arken/ark/ipfs"
 	"github.com/arken/ark/manifest"
-	files "github.com/ipfs/go-ipfs-files"
+	"github.com/schollz/progressbar/v3"
 )
 
 func init() {
@@ -25,8 +26,9 @@ func init() {
 var Pull = cmd.Sub{
 	Name:  "pull",
 	Alias: "pl",
-	Short: "Pull a file from an Arken Cluster.",
+	Short: "Pull a file, keyset, or category from an Arken Cluster.",
 	Args:  &PullArgs{},
+	Flags: &PullFlags{},
 	Run:   PullRun,
 }
 
@@ -36,6 +38,36 @@ type PullArgs struct {
 	Filepaths []string
 }
 
+// PullFlags handles the specific flags for the pull command.
+type PullFlags struct {
+	Output          string `short:"o" long:"output" desc:"Directory to save pulled files within."`
+	Jobs            int    `short:"j" long:"jobs" desc:"Number of files to pull at the same time."`
+	All             bool   `short:"a" long:"all" desc:"Pull every version of a file, suffixing each with its CID."`
+	CID             string `long:"cid" desc:"Pull the version of a file with this CID."`
+	Latest          bool   `long:"latest" desc:"Pull the version of a file from the most recently changed keyset."`
+	FailOnAmbiguous bool   `long:"fail-on-ambiguous" desc:"Fail instead of prompting when a file has multiple versions."`
+	Force           bool   `short:"f" long:"force" desc:"Overwrite existing files that differ from the pulled version."`
+}
+
+// errAmbiguous is returned when a file has multiple versions
+// and the pull isn't allowed to prompt the user to choose.
+var errAmbiguous = errors.New("multiple versions found, use --all, --cid, or --latest to choose")
+
+const (
+	// defaultPullJobs is the number of files pulled at the same time.
+	defaultPullJobs = 4
+	// pullStateFile records completed files within the output directory.
+	pullStateFile = ".ark-pull"
+	// pullTempSuffix is appended to files while they're being pulled.
+	pullTempSuffix = ".arkpart"
+)
+
+// pullJob is a single file to download from the cluster.
+type pullJob struct {
+	CID  string
+	Path string
+}
+
 // PullRun handles pulling and saving a file from an Arken cluster.
 func PullRun(r *cmd.Root, c *cmd.Sub) {
 	// Setup main application config.
@@ -43,34 +75,59 @@ func PullRun(r *cmd.Root, c *cmd.Sub) {
 
 	// Parse command arguments.
 	args := c.Args.(*PullArgs)
+	flags := c.Flags.(*PullFlags)
 
-	// Get current working directory
-	currentwd, err := os.Getwd()
-	checkError(rFlags, err)
-
-	// Swap out an alias for the corresponding url
-	alias, ok := config.Global.Manifest.Aliases[args.Manifest]
-	if ok {
-		args.Manifest = alias
+	// Use the current working directory unless an output is given.
+	output := flags.Output
+	if output == "" {
+		output = "."
 	}
+	output, err := filepath.Abs(output)
+	checkError(rFlags, err)
 
-	// Parse manifest url
-	urlPath, err := url.Parse(args.Manifest)
+	// Initialize Manifest
+	manifest, manifestPath, err := openManifest(args.Manifest, gitOptions(rFlags, args.Manifest, false))
 	checkError(rFlags, err)
 
-	// Extract manifest name from url
-	manifestName := filepath.Base(urlPath.Path)
+	// Resolve arguments into the files they refer to.
+	jobs := []pullJob{}
+	for _, path := range args.Filepaths {
+		// Keysets and categories pull every entry with their recorded paths.
+		entries, err := manifest.Resolve(path)
+		if err == nil {
+			for _, entry := range entries {
+				jobs = append(jobs, pullJob{CID: entry.CID, Path: entry.Path})
+			}
+			continue
+		}
 
-	// Generate internal manifest path from name
-	manifestPath := filepath.Join(config.Global.Manifest.Path, manifestName)
+		results, err := manifest.Search(path)
+		checkError(rFlags, err)
+		if len(results) == 0 {
+			fmt.Println("No files found matching", path)
+			os.Exit(exitFailure)
+		}
 
-	// Initialize Manifest
-	manifest, err := manifest.Init(
-		filepath.Join(manifestPath, "manifest"),
-		args.Manifest,
-		manifest.GitOptions{},
-	)
-	checkError(rFlags, err)
+		for filename, entries := range results {
+			selected, err := selectVersions(manifest, filename, entries, flags)
+			if err == errAmbiguous {
+				checkErrorCode(rFlags, exitConflict, err)
+			}
+			checkError(rFlags, err)
+			jobs = append(jobs, selected...)
+		}
+	}
+
+	// Make sure no two jobs write to the same file.
+	jobs, conflicts := dedupeJobs(jobs)
+	if len(conflicts) > 0 {
+		fmt.Println("Different files would be pulled to the same path:")
+		for _, conflict := range conflicts {
+			fmt.Printf("\t%s (%s)\n", conflict.Path, conflict.CID)
+		}
+		fmt.Println("Pull them one at a time, each into a different directory with --output.")
+		os.Exit(exitConflict)
+	}
 
 	// Create internal IPFS node for manifest
 	ipfs, err := ipfs.CreateNode(
@@ -82,60 +139,327 @@ func PullRun(r *cmd.Root, c *cmd.Sub) {
 	)
 	checkError(rFlags, err)
 
-	for _, path := range args.Filepaths {
-		results, err := manifest.Search(path)
-		checkError(rFlags, err)
+	// Open the state of any previously interrupted pull.
+	state, err := openPullState(output)
+	checkError(rFlags, err)
 
-		for filename, cids := range results {
-			i := 0
-			if len(cids) > 1 {
-				fmt.Printf("There is more than 1 file with the name: %s\n"+
-					"Which version would you like to download?\n", filename)
-
-				fmt.Printf("Select a number between 0 - %d\n", len(cids)-1)
-				for i, hash := range cids {
-					fmt.Printf("  | %d - %s", i, hash)
-				}
-
-				reader := bufio.NewReader(os.Stdin)
-				for {
-					text, err := reader.ReadString('\n')
-					checkError(rFlags, err)
-
-					if strings.ToLower(text) == "exit" {
-						return
-					}
-					i, err = strconv.Atoi(text)
-					if err == nil && i >= 0 && i < len(cids) {
-						break
-					}
-					fmt.Printf("Select a number between 0 - %d\n", len(cids)-1)
-				}
-			}
+	fmt.Printf("Pulling %d file(s) into %s\n", len(jobs), output)
+	failed := pullFiles(ipfs, state, jobs, output, flags.Jobs, flags.Force)
+	if len(failed) > 0 {
+		state.Close(false)
+		fmt.Printf("\n%d file(s) could not be pulled:\n", len(failed))
+		for _, err := range failed {
+			fmt.Println("\t", err)
+		}
+		fmt.Println("Run the same command again to resume the pull.")
+		os.Exit(exitFailure)
+	}
 
-			// Display Spinner when pulling a file.
-			doneChan := make(chan int, 1)
-			wg := sync.WaitGroup{}
-			wg.Add(1)
-			go spinnerWait(doneChan, "Pulling "+filename+"...", &wg)
+	err = state.Close(true)
+	checkError(rFlags, err)
+	fmt.Println("\nPull completed successfully!")
+}
 
-			// Pull file over IPFS
-			file, err := ipfs.Get(cids[i])
-			checkError(rFlags, err)
+// selectVersions chooses which versions of a file to pull when the
+// manifest lists more than one CID for it.
+func selectVersions(m *manifest.Manifest, filename string, entries []manifest.Entry, flags *PullFlags) ([]pullJob, error) {
+	// Different keysets may list the same file.
+	versions := []manifest.Entry{}
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		if !seen[entry.CID] {
+			versions = append(versions, entry)
+			seen[entry.CID] = true
+		}
+	}
+	name := filepath.Base(filename)
 
-			// Write IPFS file out to filesystem
-			err = files.WriteTo(file, filepath.Join(currentwd, filename))
+	switch {
+	case flags.CID != "":
+		if !seen[flags.CID] {
+			return nil, fmt.Errorf("no version of %s has the CID %s", filename, flags.CID)
+		}
+		return []pullJob{{CID: flags.CID, Path: name}}, nil
+	case len(versions) == 1:
+		return []pullJob{{CID: versions[0].CID, Path: name}}, nil
+	case flags.All:
+		// Save each version alongside the others.
+		ext := filepath.Ext(name)
+		result := []pullJob{}
+		for _, version := range versions {
+			result = append(result, pullJob{
+				CID:  version.CID,
+				Path: strings.TrimSuffix(name, ext) + "-" + version.CID + ext,
+			})
+		}
+		return result, nil
+	case flags.Latest:
+		// Choose the version from the most recently changed keyset.
+		latest, latestTime := versions[0], time.Time{}
+		for _, version := range versions {
+			modified, err := m.LastModified(version.Keyset)
 			if err != nil {
-				fmt.Printf("Could not write out the fetched CID: %s", err)
-				os.Exit(1)
+				return nil, err
+			}
+			if modified.After(latestTime) {
+				latest, latestTime = version, modified
 			}
+		}
+		return []pullJob{{CID: latest.CID, Path: name}}, nil
+	case flags.FailOnAmbiguous:
+		fmt.Printf("There is more than 1 file with the name: %s\n", filename)
+		for _, version := range versions {
+			fmt.Printf("  | %s (%s)\n", version.CID, version.Keyset)
+		}
+		return nil, errAmbiguous
+	}
+
+	fmt.Printf("There is more than 1 file with the name: %s\n"+
+		"Which version would you like to download?\n", filename)
+
+	fmt.Printf("Select a number between 0 - %d or \"exit\"\n", len(versions)-1)
+	for i, version := range versions {
+		fmt.Printf("  | %d - %s (%s)\n", i, version.CID, version.Keyset)
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		text, err := reader.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		text = strings.TrimSpace(text)
 
-			doneChan <- 0
-			wg.Wait()
+		if strings.ToLower(text) == "exit" {
+			os.Exit(0)
+		}
+		i, err := strconv.Atoi(text)
+		if err == nil && i >= 0 && i < len(versions) {
+			return []pullJob{{CID: versions[i].CID, Path: name}}, nil
+		}
+		fmt.Printf("Select a number between 0 - %d or \"exit\"\n", len(versions)-1)
+	}
+}
 
-			fmt.Println()
-			close(doneChan)
+// dedupeJobs drops jobs that pull the same file to the same path more
+// than once, such as a file listed by two keysets of a category, and
+// returns every job of a path that different files would be pulled to.
+func dedupeJobs(jobs []pullJob) (result, conflicts []pullJob) {
+	seen := make(map[string]pullJob)
+	conflicted := make(map[string]bool)
+	for _, job := range jobs {
+		dest := filepath.Clean(job.Path)
+		prev, ok := seen[dest]
+		switch {
+		case !ok:
+			seen[dest] = job
+			result = append(result, job)
+		case prev.CID != job.CID:
+			if !conflicted[dest] {
+				conflicted[dest] = true
+				conflicts = append(conflicts, prev)
+			}
+			conflicts = append(conflicts, job)
 		}
 	}
+	return result, conflicts
+}
 
+// pullFiles downloads files using a pool of workers and returns
+// the errors of any files that couldn't be pulled. Existing files that
+// differ from the pulled version are only replaced when force is set.
+func pullFiles(node *ipfs.Node, state *pullState, jobs []pullJob, output string, workers int, force bool) (failed []error) {
+	if workers <= 0 {
+		workers = defaultPullJobs
+	}
+
+	// Display an aggregate progress bar for all workers.
+	bar := progressbar.DefaultBytes(-1)
+	numDone := 0
+	lock := sync.Mutex{}
+	finish := func(err error) {
+		lock.Lock()
+		defer lock.Unlock()
+		if err != nil {
+			failed = append(failed, err)
+		}
+		numDone++
+		bar.Describe(fmt.Sprintf("%d/%d files", numDone, len(jobs)))
+	}
+	bar.Describe(fmt.Sprintf("0/%d files", len(jobs)))
+
+	input := make(chan pullJob)
+	wg := sync.WaitGroup{}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range input {
+				finish(pullFile(node, state, job, output, force, bar))
+			}
+		}()
+	}
+
+	for _, job := range jobs {
+		input <- job
+	}
+	close(input)
+	wg.Wait()
+	bar.Finish()
+
+	return failed
+}
+
+// pullFile downloads a single file into a temporary file which is
+// renamed into place once complete. Partial temporary files from an
+// interrupted pull are resumed rather than restarted.
+func pullFile(node *ipfs.Node, state *pullState, job pullJob, output string, force bool, bar io.Writer) error {
+	dest, err := pullDestination(output, job.Path)
+	if err != nil {
+		return err
+	}
+
+	// Skip files that have already been pulled.
+	if _, err := os.Stat(dest); err == nil {
+		if state.Done(job) {
+			return nil
+		}
+		valid, err := node.Verify(dest, job.CID)
+		if err != nil {
+			return fmt.Errorf("%s: %w", job.Path, err)
+		}
+		if valid {
+			return state.Complete(job)
+		}
+		if !force {
+			return fmt.Errorf("%s: already exists with different contents, use --force to overwrite it", job.Path)
+		}
+	}
+
+	// Pull file over IPFS
+	file, err := node.Get(job.CID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", job.Path, err)
+	}
+	defer file.Close()
+
+	err = os.MkdirAll(filepath.Dir(dest), os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	// Resume from the end of an existing temporary file.
+	temp := dest + pullTempSuffix
+	out, err := os.OpenFile(temp, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	info, err := out.Stat()
+	if err != nil {
+		return err
+	}
+	_, err = file.Seek(info.Size(), io.SeekStart)
+	if err != nil {
+		return fmt.Errorf("%s: %w", job.Path, err)
+	}
+
+	// Write IPFS file out to filesystem
+	_, err = io.Copy(io.MultiWriter(out, bar), file)
+	if err != nil {
+		return fmt.Errorf("%s: %w", job.Path, err)
+	}
+	err = out.Close()
+	if err != nil {
+		return err
+	}
+
+	// Check the pulled content against the CID from the manifest.
+	valid, err := node.Verify(temp, job.CID)
+	if err != nil {
+		return fmt.Errorf("%s: %w", job.Path, err)
+	}
+	if !valid {
+		os.Remove(temp)
+		return fmt.Errorf("%s: pulled content does not match %s and was deleted", job.Path, job.CID)
+	}
+
+	err = os.Rename(temp, dest)
+	if err != nil {
+		return err
+	}
+	return state.Complete(job)
+}
+
+// pullDestination joins a path recorded within a keyset onto the output
+// directory, refusing paths that would escape the output directory.
+func pullDestination(output, path string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(path))
+	if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", errors.New("refusing to write outside of the output directory: " + path)
+	}
+	return filepath.Join(output, clean), nil
+}
+
+// pullState records the files completed by a pull so that
+// an interrupted pull can resume where it stopped.
+type pullState struct {
+	path string
+	done map[string]string
+	file *os.File
+	lock sync.Mutex
+}
+
+// openPullState reads the state left within an output
+// directory by a previous pull, if there is one.
+func openPullState(output string) (*pullState, error) {
+	result := &pullState{
+		path: filepath.Join(output, pullStateFile),
+		done: make(map[string]string),
+	}
+
+	err := os.MkdirAll(output, os.ModePerm)
+	if err != nil {
+		return nil, err
+	}
+
+	// Each completed file is recorded as a line of "CID  path".
+	result.file, err = os.OpenFile(result.path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	scanner := bufio.NewScanner(result.file)
+	for scanner.Scan() {
+		data := strings.SplitN(scanner.Text(), "  ", 2)
+		if len(data) == 2 {
+			result.done[data[1]] = data[0]
+		}
+	}
+	return result, scanner.Err()
+}
+
+// Done reports whether a file was completed by a previous pull.
+func (s *pullState) Done(job pullJob) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.done[job.Path] == job.CID
+}
+
+// Complete records that a file has been pulled.
+func (s *pullState) Complete(job pullJob) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.done[job.Path] = job.CID
+	_, err := s.file.WriteString(job.CID + "  " + job.Path + "\n")
+	return err
+}
+
+// Close closes the state file, removing it if the pull finished.
+func (s *pullState) Close(finished bool) error {
+	err := s.file.Close()
+	if err != nil || !finished {
+		return err
+	}
+	return os.Remove(s.path)
 }
@@ -1,14 +1,12 @@
 package cli
 
 import (
-	"bufio"
 	"fmt"
 	"os"
 	"path/filepath"
-	"sort"
-	"strings"
 
 	"github.com/DataDrake/cli-ng/v2/cmd"
+	"github.com/arken/ark/stage"
 )
 
 func init() {
@@ -47,61 +45,68 @@ func RemoveRun(r *cmd.Root, c *cmd.Sub) {
 		os.Exit(1)
 	}
 
-	// Initialize file cache and paths from args
-	fileCache := make(map[string]bool)
+	// Initialize paths from args
 	argPaths := c.Args.(*RemoveArgs).Paths
 
-	// Open previous cache if exists
-	f, err := os.Open(AddedFilesPath)
-	if err == nil {
-		// Import existing cache from file.
-		scanner := bufio.NewScanner(f)
-		for scanner.Scan() {
-			if len(scanner.Text()) > 0 {
-				fileCache[scanner.Text()] = true
-			}
-		}
-		f.Close()
-	}
+	// Open the staging index.
+	index, err := stage.Read()
+	checkError(rFlags, err)
 
-	// Iterate through paths to check they exist
-	// if adding a dir add all sub files within that dir.
+	// Iterate through paths and match them against the staged files
+	// so that files already deleted from disk can still be removed.
+	// Ignore patterns aren't applied, as they only decide what is
+	// staged, and files added with --force must be removable too.
+	numRemoved := 0
 	for _, path := range argPaths {
-		stat, err := os.Stat(path)
-		checkError(rFlags, err)
-
-		// Walk through a directory and add all children files.
-		if stat.IsDir() {
-			filepath.Walk(path, func(path string, info os.FileInfo, err error) error {
-				if !info.IsDir() {
-					delete(fileCache, path)
-				}
-				return nil
+		var paths []string
+		clean := filepath.Clean(path)
+
+		if isGlob(path) {
+			paths = stagedMatching(index, func(file string) bool {
+				return matchGlobOrParent(path, file)
 			})
 		} else {
-			delete(fileCache, path)
+			stat, err := os.Stat(path)
+			if err != nil && !os.IsNotExist(err) {
+				checkError(rFlags, err)
+			}
+
+			// Remove explicitly named files.
+			if (err != nil || !stat.IsDir()) && index.Remove(clean) {
+				numRemoved++
+				continue
+			}
+
+			// Find all of the staged children of a directory.
+			paths = stagedMatching(index, func(file string) bool {
+				return inDirectory(clean, file)
+			})
+		}
+
+		if len(paths) == 0 {
+			fmt.Printf("No staged files match %v\n", path)
 		}
-	}
 
-	// Create a string of the keys of the cache map.
-	keys := make([]string, 0, len(fileCache))
-	for k := range fileCache {
-		keys = append(keys, k)
+		for _, file := range paths {
+			if index.Remove(file) {
+				numRemoved++
+			}
+		}
 	}
 
-	// Sort output cache for readability
-	sort.Strings(keys)
+	// Write out the updated index.
+	err = index.Write()
+	checkError(rFlags, err)
 
-	if len(keys) > 0 {
-		f, err = os.Create(AddedFilesPath)
-		checkError(rFlags, err)
-		defer f.Close()
+	fmt.Printf("Removed %d file(s), %d file(s) still staged for submission.\n", numRemoved, index.Len())
+}
 
-		// Write out cache to file.
-		_, err = f.WriteString(strings.Join(keys, "\n") + "\n")
-		checkError(rFlags, err)
-	} else {
-		err = os.Remove(AddedFilesPath)
-		checkError(rFlags, err)
+// stagedMatching returns the staged files that match reports true for.
+func stagedMatching(index *stage.Index, match func(file string) bool) (result []string) {
+	for _, file := range index.Paths() {
+		if match(file) {
+			result = append(result, file)
+		}
 	}
+	return result
 }
@@ -0,0 +1,172 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/DataDrake/cli-ng/v2/cmd"
+	"github.com/arken/ark/config"
+	"github.com/arken/ark/ipfs"
+	"github.com/arken/ark/keyset"
+	"github.com/arken/ark/manifest"
+	"github.com/arken/ark/stage"
+)
+
+func init() {
+	cmd.Register(&Replace)
+}
+
+// Replace publishes a corrected version of a file within a keyset.
+var Replace = cmd.Sub{
+	Name:  "replace",
+	Alias: "rp",
+	Short: "Replace a file within a keyset published within a manifest.",
+	Args:  &ReplaceArgs{},
+	Flags: &ReplaceFlags{},
+	Run:   ReplaceRun,
+}
+
+// ReplaceArgs handles the specific arguments for the replace command.
+type ReplaceArgs struct {
+	Manifest string
+	Keyset   string
+	Target   string
+	File     string
+}
+
+// ReplaceFlags handles the specific flags for the replace command.
+type ReplaceFlags struct {
+	IsPR   bool   `short:"p" long:"pull-request" desc:"Jump straight into submitting a pull request"`
+	Direct bool   `long:"direct" desc:"Push straight to the manifest instead of submitting a pull request."`
+	Yes    bool   `short:"y" long:"yes" desc:"Never prompt for input, fail with a non-zero exit code instead."`
+	Commit string `short:"m" long:"message" desc:"Commit message to use instead of the generated one."`
+}
+
+// ReplaceRun points the entries matching a path or CID within a keyset at the
+// contents of a local file and submits the change through the same workflow
+// as a submission.
+func ReplaceRun(r *cmd.Root, c *cmd.Sub) {
+	// Setup main application config.
+	rFlags := rootInit(r)
+
+	args := c.Args.(*ReplaceArgs)
+	flags := c.Flags.(*ReplaceFlags)
+
+	info, err := os.Stat(args.File)
+	checkErrorCode(rFlags, exitUsage, err)
+	if info.IsDir() {
+		checkErrorCode(rFlags, exitUsage, fmt.Errorf("%s is a directory", args.File))
+	}
+
+	// Swap out an alias for the corresponding url
+	alias, ok := config.Global.Manifest.Aliases[args.Manifest]
+	if ok {
+		args.Manifest = alias
+	}
+
+	checkGitIdentity(rFlags, !flags.Yes)
+	checkPublishFlags(rFlags, flags.IsPR, flags.Direct)
+	loadAuth(rFlags, args.Manifest, !flags.Yes)
+
+	// Initialize Manifest
+	m, manifestPath, err := openManifest(args.Manifest, gitOptions(rFlags, args.Manifest, true))
+	checkError(rFlags, err)
+
+	rel, path, err := keysetPath(manifestPath, args.Keyset)
+	checkErrorCode(rFlags, exitUsage, err)
+
+	ks, err := keyset.ReadFile(path)
+	if os.IsNotExist(err) {
+		err = fmt.Errorf("keyset %s not found", rel)
+	}
+	checkErrorCode(rFlags, exitUsage, err)
+
+	// Hash the replacement file.
+	node, err := ipfs.CreateOfflineNode()
+	checkError(rFlags, err)
+
+	cid, err := node.Hash(args.File)
+	checkError(rFlags, err)
+
+	mimeType, err := stage.DetectType(args.File)
+	checkError(rFlags, err)
+
+	// Point every matching entry at the replacement file.
+	body := &strings.Builder{}
+	replacement := keyset.Entry{CID: cid, Metadata: keyset.Metadata{Size: info.Size(), Type: mimeType}}
+	err = replaceEntries(ks, args.Target, replacement, rel, body)
+	checkErrorCode(rFlags, exitUsage, err)
+	if body.Len() == 0 {
+		fmt.Printf("%s already matches %s, nothing to replace.\n", args.Target, args.File)
+		return
+	}
+
+	// Describe the change for the commit and pull request.
+	title := fmt.Sprintf("Replace %s in %s", args.Target, rel)
+	commit := title + "\n\n" + body.String()
+	if flags.Commit != "" {
+		commit = flags.Commit
+	}
+
+	fmt.Println(title)
+	fmt.Print(body.String())
+	if !flags.Yes && !queryUserConfirm("Submit this change?") {
+		fmt.Println("Replacement aborted.")
+		os.Exit(exitFailure)
+	}
+
+	pr := publishKeyset(rFlags, m, publication{
+		Path:   path,
+		Branch: m.SubmissionBranch("replace", filepath.ToSlash(rel)),
+		Commit: commit,
+		Title:  title,
+		Body:   body.String(),
+		IsPR:   flags.IsPR,
+		Direct: flags.Direct,
+	}, func() (manifest.Generated, error) {
+		// Replace within the keyset on the branch being published to,
+		// which may already hold changes waiting to be merged.
+		ks, err := keyset.ReadFile(path)
+		if err != nil {
+			return manifest.Generated{}, err
+		}
+		err = replaceEntries(ks, args.Target, replacement, rel, io.Discard)
+		if err != nil {
+			return manifest.Generated{}, err
+		}
+		out, _, err := m.Generate(ks)
+		return out, err
+	})
+	recordSubmission(rFlags, args.Manifest, filepath.ToSlash(rel), title, pr)
+
+	fmt.Println("Completed Replacement Successfully!")
+	printFollowPR(pr)
+	fmt.Println("Once the change is accepted, add the file and run ark upload to publish it.")
+}
+
+// replaceEntries points every entry of a keyset matching the path or CID
+// of target at the CID and metadata of a replacement, describing each
+// change it makes to changes.
+func replaceEntries(ks *keyset.Keyset, target string, replacement keyset.Entry, rel string, changes io.Writer) error {
+	matched := false
+	for i, entry := range ks.Entries {
+		if entry.Path != target && entry.CID != target {
+			continue
+		}
+		matched = true
+		if entry.CID == replacement.CID {
+			continue
+		}
+		fmt.Fprintf(changes, "- %s: %s -> %s\n", entry.Path, entry.CID, replacement.CID)
+		ks.Entries[i].CID = replacement.CID
+		ks.Entries[i].Size = replacement.Size
+		ks.Entries[i].Type = replacement.Type
+	}
+	if !matched {
+		return fmt.Errorf("%s is not listed in %s", target, rel)
+	}
+	return nil
+}
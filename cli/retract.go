@@ -0,0 +1,174 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/DataDrake/cli-ng/v2/cmd"
+	"github.com/arken/ark/config"
+	"github.com/arken/ark/keyset"
+	"github.com/arken/ark/manifest"
+)
+
+func init() {
+	cmd.Register(&Retract)
+}
+
+// Retract removes entries from a keyset published within a manifest.
+var Retract = cmd.Sub{
+	Name:  "retract",
+	Alias: "rt",
+	Short: "Remove files from a keyset published within a manifest.",
+	Args:  &RetractArgs{},
+	Flags: &RetractFlags{},
+	Run:   RetractRun,
+}
+
+// RetractArgs handles the specific arguments for the retract command.
+type RetractArgs struct {
+	Manifest string
+	Keyset   string
+	Targets  []string
+}
+
+// RetractFlags handles the specific flags for the retract command.
+type RetractFlags struct {
+	IsPR   bool   `short:"p" long:"pull-request" desc:"Jump straight into submitting a pull request"`
+	Direct bool   `long:"direct" desc:"Push straight to the manifest instead of submitting a pull request."`
+	Yes    bool   `short:"y" long:"yes" desc:"Never prompt for input, fail with a non-zero exit code instead."`
+	Commit string `short:"m" long:"message" desc:"Commit message to use instead of the generated one."`
+}
+
+// RetractRun removes the entries matching each path or CID from a keyset and
+// submits the change through the same workflow as a submission.
+func RetractRun(r *cmd.Root, c *cmd.Sub) {
+	// Setup main application config.
+	rFlags := rootInit(r)
+
+	args := c.Args.(*RetractArgs)
+	flags := c.Flags.(*RetractFlags)
+
+	// Swap out an alias for the corresponding url
+	alias, ok := config.Global.Manifest.Aliases[args.Manifest]
+	if ok {
+		args.Manifest = alias
+	}
+
+	checkGitIdentity(rFlags, !flags.Yes)
+	checkPublishFlags(rFlags, flags.IsPR, flags.Direct)
+	loadAuth(rFlags, args.Manifest, !flags.Yes)
+
+	// Initialize Manifest
+	m, manifestPath, err := openManifest(args.Manifest, gitOptions(rFlags, args.Manifest, true))
+	checkError(rFlags, err)
+
+	rel, path, err := keysetPath(manifestPath, args.Keyset)
+	checkErrorCode(rFlags, exitUsage, err)
+
+	ks, err := keyset.ReadFile(path)
+	if os.IsNotExist(err) {
+		err = fmt.Errorf("keyset %s not found", rel)
+	}
+	checkErrorCode(rFlags, exitUsage, err)
+
+	_, retracted, err := retractEntries(ks, args.Targets, rel)
+	checkErrorCode(rFlags, exitUsage, err)
+
+	// Describe the change for the commit and pull request.
+	title := fmt.Sprintf("Retract %d file(s) from %s", len(retracted), rel)
+	body := &strings.Builder{}
+	for _, entry := range retracted {
+		fmt.Fprintf(body, "- %s (%s)\n", entry.Path, entry.CID)
+	}
+	commit := title + "\n\n" + body.String()
+	if flags.Commit != "" {
+		commit = flags.Commit
+	}
+
+	fmt.Println(title)
+	fmt.Print(body.String())
+	if !flags.Yes && !queryUserConfirm("Submit this change?") {
+		fmt.Println("Retraction aborted.")
+		os.Exit(exitFailure)
+	}
+
+	pr := publishKeyset(rFlags, m, publication{
+		Path:   path,
+		Branch: m.SubmissionBranch("retract", filepath.ToSlash(rel)),
+		Commit: commit,
+		Title:  title,
+		Body:   body.String(),
+		IsPR:   flags.IsPR,
+		Direct: flags.Direct,
+	}, func() (manifest.Generated, error) {
+		// Retract from the keyset on the branch being published to,
+		// which may already hold changes waiting to be merged.
+		ks, err := keyset.ReadFile(path)
+		if err != nil {
+			return manifest.Generated{}, err
+		}
+		kept, _, err := retractEntries(ks, args.Targets, rel)
+		if err != nil || len(kept) == 0 {
+			// Remove the keyset entirely once nothing is left in it.
+			return manifest.Generated{}, err
+		}
+		out, _, err := m.Generate(&keyset.Keyset{Header: ks.Header, Entries: kept})
+		return out, err
+	})
+	recordSubmission(rFlags, args.Manifest, filepath.ToSlash(rel), title, pr)
+
+	fmt.Println("Completed Retraction Successfully!")
+	printFollowPR(pr)
+}
+
+// retractEntries splits the entries of a keyset into those to keep and
+// those matching the path or CID of a target, which are retracted.
+func retractEntries(ks *keyset.Keyset, targets []string, rel string) (kept, retracted []keyset.Entry, err error) {
+	found := make(map[string]bool, len(targets))
+	for _, entry := range ks.Entries {
+		matched := false
+		for _, target := range targets {
+			if entry.Path == target || entry.CID == target {
+				found[target] = true
+				matched = true
+			}
+		}
+		if matched {
+			retracted = append(retracted, entry)
+		} else {
+			kept = append(kept, entry)
+		}
+	}
+	for _, target := range targets {
+		if !found[target] {
+			return kept, retracted, fmt.Errorf("%s is not listed in %s", target, rel)
+		}
+	}
+	return kept, retracted, nil
+}
+
+// keysetPath resolves the name of a keyset relative to the root of a
+// manifest into its cleaned relative path and its path on disk.
+func keysetPath(manifestPath, name string) (rel, path string, err error) {
+	rel = filepath.Clean(strings.TrimSpace(name))
+	if !strings.HasSuffix(rel, ".ks") {
+		rel += ".ks"
+	}
+	if filepath.IsAbs(rel) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return rel, "", errors.New("path backtracking (\"..\") is not allowed in the keyset")
+	}
+	return rel, filepath.Join(manifestPath, "manifest", rel), nil
+}
+
+func queryUserConfirm(question string) bool {
+	fmt.Printf("%v (y/[n]) ", question)
+	reader := bufio.NewReader(os.Stdin)
+	input, _ := reader.ReadString('\n')
+	input = strings.ToLower(strings.TrimSpace(input))
+
+	return input == "y" || input == "yes"
+}
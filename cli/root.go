@@ -11,10 +11,19 @@ import (
 
 	"github.com/DataDrake/cli-ng/v2/cmd"
 	"github.com/arken/ark/config"
+	"github.com/arken/ark/credential"
+	"github.com/arken/ark/manifest"
+	"github.com/arken/ark/manifest/upstream"
+	"golang.org/x/term"
 )
 
-// AddedFilesPath is the file cache location.
-const AddedFilesPath string = ".ark/added_files"
+// Exit codes reported by Ark so scripts can tell failures apart.
+const (
+	exitFailure  = 1
+	exitUsage    = 2
+	exitAuth     = 3
+	exitConflict = 4
+)
 
 //GlobalFlags contains the flags for commands.
 type GlobalFlags struct {
@@ -51,6 +60,16 @@ func rootInit(r *cmd.Root) *GlobalFlags {
 	err := config.Init(path)
 	checkError(rFlags, err)
 
+	// Register the upstreams of any self-hosted or enterprise instances.
+	for host, up := range config.Global.Upstreams {
+		err = upstream.Configure(host, up.Type, upstream.Options{
+			URL:      up.URL,
+			APIURL:   up.APIURL,
+			ClientID: up.ClientID,
+		})
+		checkErrorCode(rFlags, exitUsage, err)
+	}
+
 	// Return setup root flags
 	return rFlags
 }
@@ -59,13 +78,117 @@ func rootInit(r *cmd.Root) *GlobalFlags {
 // or debug error report based on the verbosity of the
 // application.
 func checkError(flags *GlobalFlags, err error) {
+	checkErrorCode(flags, exitFailure, err)
+}
+
+// checkErrorCode behaves like checkError but exits with
+// the provided exit code.
+func checkErrorCode(flags *GlobalFlags, code int, err error) {
 	if err != nil {
 		if flags.Verbose {
-			log.Fatal(err)
+			log.Println(err)
+		} else {
+			fmt.Println(err)
 		}
-		fmt.Println(err)
-		os.Exit(1)
+		os.Exit(code)
+	}
+}
+
+// openManifest swaps out an alias for its manifest url and then clones or
+// pulls the manifest. It returns the manifest along with the directory
+// Ark uses to store its local data.
+func openManifest(location string, opts manifest.GitOptions) (*manifest.Manifest, string, error) {
+	// Swap out an alias for the corresponding url
+	alias, ok := config.Global.Manifest.Aliases[location]
+	if ok {
+		location = alias
+	}
+
+	// Parse manifest url
+	urlPath, err := manifest.ParseLocation(location)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Generate internal manifest path from name
+	manifestPath := filepath.Join(config.Global.Manifest.Path, filepath.Base(urlPath.Path))
+
+	// Initialize Manifest
+	result, err := manifest.Init(
+		filepath.Join(manifestPath, "manifest"),
+		location,
+		opts,
+	)
+	return result, manifestPath, err
+}
+
+// gitOptions returns the git identity and credentials used to clone,
+// pull, and change the manifest at location. A token set in the config
+// or environment takes precedence over the one stored for its host.
+// Commands that only read the manifest set write to false, so they
+// treat a failing credential store as having no credential and never
+// let a credential helper ask the user to log in.
+func gitOptions(rFlags *GlobalFlags, location string, write bool) manifest.GitOptions {
+	opts := manifest.GitOptions{
+		Name:     config.Global.Git.Name,
+		Username: config.Global.Git.Username,
+		Email:    config.Global.Git.Email,
+		Token:    config.Global.Git.Token,
+		SSHKey:   config.Global.Git.SSHKey,
+	}
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		opts.Passphrase = queryPassphrase()
+	}
+
+	host := credentialHost(location)
+	if opts.Token == "" && host != "" {
+		store := credentialStore(rFlags)
+		if helper, ok := store.(*credential.HelperStore); ok && !write {
+			helper.NoPrompt = true
+		}
+		cred, err := store.Get(host)
+		if err != nil && err != credential.ErrNotFound && write {
+			checkError(rFlags, err)
+		}
+		if err == nil {
+			opts.Username = cred.Username
+			opts.Token = cred.Token
+		}
+	}
+	return opts
+}
+
+// queryPassphrase returns a function asking for the passphrase of an
+// encrypted SSH key, which asks once for each key.
+func queryPassphrase() func(key string) (string, error) {
+	passphrases := map[string]string{}
+	return func(key string) (string, error) {
+		if passphrase, ok := passphrases[key]; ok {
+			return passphrase, nil
+		}
+		fmt.Printf("Enter passphrase for key '%s': ", key)
+		input, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Println()
+		if err != nil {
+			return "", err
+		}
+		passphrases[key] = string(input)
+		return passphrases[key], nil
+	}
+}
+
+// formatBytes converts a number of bytes into a human readable size.
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
 	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
 }
 
 // spinner is an array of the progression of the spinner.
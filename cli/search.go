@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/DataDrake/cli-ng/v2/cmd"
+	"github.com/arken/ark/manifest"
+)
+
+func init() {
+	cmd.Register(&Search)
+}
+
+// Search finds files published within a manifest.
+var Search = cmd.Sub{
+	Name:  "search",
+	Alias: "sr",
+	Short: "Search for files published within a manifest.",
+	Args:  &SearchArgs{},
+	Flags: &SearchFlags{},
+	Run:   SearchRun,
+}
+
+// SearchArgs handles the specific arguments for the search command.
+type SearchArgs struct {
+	Manifest string
+	Pattern  string
+}
+
+// SearchFlags handles the specific flags for the search command.
+type SearchFlags struct {
+	Glob  bool `short:"g" long:"glob" desc:"Match the pattern as a glob against file paths."`
+	Regex bool `short:"r" long:"regex" desc:"Match the pattern as a regular expression against file paths."`
+	CID   bool `long:"cid" desc:"Find where a CID is published instead of matching file paths."`
+}
+
+// SearchRun lists the entries within a manifest's keysets that match a pattern.
+func SearchRun(r *cmd.Root, c *cmd.Sub) {
+	// Setup main application config.
+	rFlags := rootInit(r)
+
+	args := c.Args.(*SearchArgs)
+	flags := c.Flags.(*SearchFlags)
+
+	match, err := searchMatcher(args.Pattern, flags)
+	checkErrorCode(rFlags, exitUsage, err)
+
+	// Initialize Manifest
+	m, _, err := openManifest(args.Manifest, gitOptions(rFlags, args.Manifest, false))
+	checkError(rFlags, err)
+
+	results, err := m.Find(match)
+	checkError(rFlags, err)
+
+	if len(results) == 0 {
+		fmt.Println("No files found matching", args.Pattern)
+		os.Exit(exitFailure)
+	}
+
+	// Print results in aligned columns.
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CATEGORY\tKEYSET\tCID\tPATH")
+	for _, entry := range results {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+			entry.Category(),
+			filepath.Base(entry.Keyset),
+			entry.CID,
+			entry.Path,
+		)
+	}
+	w.Flush()
+	fmt.Printf("\n%d result(s)\n", len(results))
+}
+
+// searchMatcher builds the function used to match manifest
+// entries against a search pattern.
+func searchMatcher(pattern string, flags *SearchFlags) (func(entry manifest.Entry) bool, error) {
+	switch {
+	case flags.CID && (flags.Glob || flags.Regex):
+		return nil, errors.New("--cid cannot be combined with --glob or --regex")
+	case flags.Glob && flags.Regex:
+		return nil, errors.New("--glob and --regex cannot be used together")
+	case flags.CID:
+		return func(entry manifest.Entry) bool {
+			return entry.CID == pattern
+		}, nil
+	case flags.Glob:
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, err
+		}
+		return func(entry manifest.Entry) bool {
+			// Match either the full path or just the file's name.
+			matched, _ := filepath.Match(pattern, filepath.Base(entry.Path))
+			return matched || matchGlob(pattern, entry.Path)
+		}, nil
+	case flags.Regex:
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		return func(entry manifest.Entry) bool {
+			return re.MatchString(entry.Path)
+		}, nil
+	}
+
+	pattern = strings.ToLower(pattern)
+	return func(entry manifest.Entry) bool {
+		return strings.Contains(strings.ToLower(entry.Path), pattern)
+	}, nil
+}
@@ -1,13 +1,12 @@
 package cli
 
 import (
-	"bufio"
-	"bytes"
 	"fmt"
-	"io"
 	"os"
+	"strings"
 
 	"github.com/DataDrake/cli-ng/v2/cmd"
+	"github.com/arken/ark/stage"
 )
 
 func init() {
@@ -20,13 +19,31 @@ var Status = cmd.Sub{
 	Alias: "s",
 	Short: "View what files are currently staged for submission.",
 	Args:  &StatusArgs{},
+	Flags: &StatusFlags{},
 	Run:   StatusRun,
 }
 
 // StatusArgs handles the specific arguments for the status command.
 type StatusArgs struct {
+	Manifest []string `zero:"true"`
 }
 
+// StatusFlags handles the specific flags for the status command.
+type StatusFlags struct {
+	All bool `short:"a" long:"all" desc:"List every staged file, even beyond the first 50."`
+}
+
+// statusLimit is the number of staged files listed without --all.
+const statusLimit = 50
+
+// Classifications of staged files reported by the status command.
+const (
+	statusNew       = "new"
+	statusModified  = "modified"
+	statusMissing   = "missing"
+	statusPublished = "published"
+)
+
 // StatusRun handles the execution of the status command.
 func StatusRun(r *cmd.Root, c *cmd.Sub) {
 	// Setup main application config.
@@ -45,49 +62,85 @@ func StatusRun(r *cmd.Root, c *cmd.Sub) {
 		os.Exit(1)
 	}
 
-	// Open previous cache if exists
-	f, err := os.Open(AddedFilesPath)
-	if err != nil && os.IsNotExist(err) {
-		fmt.Println(0, "file(s) currently staged for submission")
-		return
-	}
-	checkError(rFlags, err)
-	defer f.Close()
+	args := c.Args.(*StatusArgs)
+	flags := c.Flags.(*StatusFlags)
 
-	lines, err := lineCounter(f)
+	// Open the staging index.
+	index, err := stage.Read()
 	checkError(rFlags, err)
 
-	_, err = f.Seek(0, 0)
-	checkError(rFlags, err)
+	// Look up the files already published within a manifest.
+	published := map[string][]string{}
+	if len(args.Manifest) > 0 {
+		m, _, err := openManifest(args.Manifest[0], gitOptions(rFlags, args.Manifest[0], false))
+		checkError(rFlags, err)
 
-	fmt.Println(lines, "file(s) currently staged for submission")
-	if lines <= 50 {
-		scanner := bufio.NewScanner(f)
-		for scanner.Scan() {
-			if len(scanner.Text()) > 0 {
-				fmt.Println("\t", scanner.Text())
-			}
-		}
+		published, err = m.Locate()
+		checkError(rFlags, err)
 	}
-}
-
-// efficient lineCounter function from
-// https://stackoverflow.com/a/24563853
-func lineCounter(r io.Reader) (int, error) {
-	buf := make([]byte, 32*1024)
-	count := 0
-	lineSep := []byte{'\n'}
 
-	for {
-		c, err := r.Read(buf)
-		count += bytes.Count(buf[:c], lineSep)
+	lines := index.Len()
+	fmt.Println(lines, "file(s) currently staged for submission")
 
+	// Check staged files against the repository's ignore patterns.
+	ignores := newIgnoreList(false)
+	numIgnored := 0
+
+	counts := make(map[string]int)
+	sizes := make(map[string]int64)
+	for i, path := range index.Paths() {
+		entry := index.Entries[path]
+		ignored, err := ignores.Ignored(path, false)
+		checkError(rFlags, err)
+
+		// Classify the file against its staged state.
+		status := statusNew
+		size := entry.Size
+		detail := ""
+		info, err := os.Stat(path)
 		switch {
-		case err == io.EOF:
-			return count, nil
-
+		case os.IsNotExist(err):
+			status = statusMissing
+			size = 0
 		case err != nil:
-			return count, err
+			checkError(rFlags, err)
+		case entry.Modified(info):
+			status = statusModified
+			size = info.Size()
+		case entry.CID != "" && len(published[entry.CID]) > 0:
+			status = statusPublished
+			detail = " (in " + strings.Join(published[entry.CID], ", ") + ")"
+		}
+		counts[status]++
+		sizes[status] += size
+
+		if ignored {
+			numIgnored++
+			detail += " (ignored)"
+		}
+		if i < statusLimit || flags.All {
+			fmt.Printf("\t%-10s %s%s\n", status+":", path, detail)
+		}
+	}
+	if lines > statusLimit && !flags.All {
+		fmt.Printf("\t... and %d more, use --all to list every file.\n", lines-statusLimit)
+	}
+
+	if lines > 0 {
+		fmt.Println()
+		total := int64(0)
+		for _, status := range []string{statusNew, statusModified, statusMissing, statusPublished} {
+			if counts[status] > 0 {
+				fmt.Printf("%-10s %d file(s), %s\n", status+":", counts[status], formatBytes(sizes[status]))
+				total += sizes[status]
+			}
 		}
+		fmt.Printf("%-10s %s\n", "total:", formatBytes(total))
+	}
+	if counts[statusModified] > 0 {
+		fmt.Println("Modified files will be hashed again when you submit or run \"ark add\".")
+	}
+	if numIgnored > 0 {
+		fmt.Println(numIgnored, "staged file(s) match an ignore pattern, use \"ark remove <path>\" to unstage them.")
 	}
 }
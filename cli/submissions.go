@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/DataDrake/cli-ng/v2/cmd"
+	"github.com/arken/ark/manifest"
+	"github.com/arken/ark/manifest/upstream"
+	"github.com/arken/ark/stage"
+)
+
+func init() {
+	cmd.Register(&Submissions)
+}
+
+// Submissions follows the keysets submitted from an Ark repository.
+var Submissions = cmd.Sub{
+	Name:  "submissions",
+	Alias: "sm",
+	Short: "Follow the pull requests of your submissions.",
+	Args:  &SubmissionsArgs{},
+	Flags: &SubmissionsFlags{},
+	Run:   SubmissionsRun,
+}
+
+// SubmissionsArgs handles the specific arguments for the submissions command.
+type SubmissionsArgs struct {
+}
+
+// SubmissionsFlags handles the specific flags for the submissions command.
+type SubmissionsFlags struct {
+	Comments bool `long:"comments" desc:"Print the comments left on each pull request."`
+	Offline  bool `long:"offline" desc:"Show the last recorded states without checking upstream."`
+}
+
+// SubmissionsRun handles the execution of the submissions command.
+func SubmissionsRun(r *cmd.Root, c *cmd.Sub) {
+	// Setup main application config.
+	rFlags := rootInit(r)
+
+	// Check if .ark directory already exists.
+	info, err := os.Stat(".ark")
+
+	// If .ark does not exist notify the user to run
+	// ark init() first.
+	if os.IsNotExist(err) || !info.IsDir() {
+		fmt.Printf("This is not an Ark repository! Please run\n\n" +
+			"    ark init\n\n" +
+			"Before attempting to follow any submissions.\n",
+		)
+		os.Exit(1)
+	}
+
+	flags := c.Flags.(*SubmissionsFlags)
+
+	records, err := stage.ReadSubmissions()
+	checkError(rFlags, err)
+	if len(records) == 0 {
+		fmt.Println("No submissions recorded.")
+		return
+	}
+
+	// Check the pull requests of submissions that are still open.
+	comments := make([][]upstream.Comment, len(records))
+	failed := make([]error, len(records))
+	for i, record := range records {
+		if flags.Offline || record.State != upstream.PrOpen {
+			continue
+		}
+		status, err := manifest.PrStatus(record.Manifest, gitOptions(rFlags, record.Manifest, false), upstream.PullRequest{
+			Number: record.Number,
+			URL:    record.URL,
+			Branch: record.Branch,
+		})
+		if err != nil {
+			failed[i] = err
+			continue
+		}
+		records[i].State = status.State
+		records[i].Checked = time.Now().UTC()
+		comments[i] = status.Comments
+	}
+	err = stage.WriteSubmissions(records)
+	checkError(rFlags, err)
+
+	uploads := []string{}
+	merged := map[string]bool{}
+	for i, record := range records {
+		fmt.Printf("%s [%s]\n", record.Title, record.State)
+		fmt.Printf("\tkeyset:   %s\n", record.Keyset)
+		fmt.Printf("\tmanifest: %s\n", record.Manifest)
+		if record.URL != "" {
+			fmt.Printf("\tpull:     %s\n", record.URL)
+		} else if record.Branch != "" {
+			fmt.Printf("\tbranch:   %s\n", record.Branch)
+		}
+		if failed[i] != nil {
+			fmt.Printf("\tCould not check the pull request: %s\n", failed[i])
+		}
+
+		switch {
+		case len(comments[i]) > 0 && flags.Comments:
+			for _, comment := range comments[i] {
+				fmt.Printf("\t%s on %s:\n", comment.Author, comment.Created.Local().Format("2006-01-02 15:04"))
+				for _, line := range strings.Split(strings.TrimSpace(comment.Body), "\n") {
+					fmt.Printf("\t\t%s\n", line)
+				}
+			}
+		case len(comments[i]) > 0:
+			fmt.Printf("\t%d comment(s), use --comments to read them.\n", len(comments[i]))
+		}
+
+		published := record.State == upstream.PrMerged || record.State == stage.StatePushed
+		if published && !merged[record.Manifest] {
+			merged[record.Manifest] = true
+			uploads = append(uploads, record.Manifest)
+		}
+		fmt.Println()
+	}
+
+	// Merged and pushed keysets are ready to have their files uploaded.
+	for _, location := range uploads {
+		fmt.Printf("Submissions to %s were published, run \"ark upload %s\" to upload their files.\n", location, location)
+	}
+}
@@ -2,12 +2,13 @@ package cli
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
 	"math"
-	"net/url"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"strings"
 	"time"
@@ -15,10 +16,12 @@ import (
 	"github.com/DataDrake/cli-ng/v2/cmd"
 	"github.com/arken/ark/config"
 	"github.com/arken/ark/ipfs"
+	"github.com/arken/ark/keyset"
 	"github.com/arken/ark/manifest"
 	"github.com/arken/ark/manifest/upstream"
 	"github.com/arken/ark/parser"
-	"github.com/schollz/progressbar/v3"
+	"github.com/arken/ark/stage"
+	"golang.org/x/term"
 )
 
 func init() {
@@ -42,7 +45,36 @@ type SubmitArgs struct {
 
 // SubmitFlags handles the specific flags for the submit command.
 type SubmitFlags struct {
-	IsPR bool `short:"p" long:"pull-request" desc:"Jump straight into submitting a pull request"`
+	IsPR        bool   `short:"p" long:"pull-request" desc:"Jump straight into submitting a pull request"`
+	Direct      bool   `long:"direct" desc:"Push straight to the manifest instead of submitting a pull request."`
+	Yes         bool   `short:"y" long:"yes" desc:"Never prompt for input, fail with a non-zero exit code instead."`
+	Application string `short:"a" long:"application" desc:"Read the submission application from a file."`
+	Category    string `long:"category" desc:"Category path of the keyset within the manifest."`
+	Filename    string `long:"filename" desc:"Name of the keyset file to create."`
+	Title       string `long:"title" desc:"Title of the submission."`
+	Commit      string `short:"m" long:"message" desc:"Commit message for the submission."`
+	PRBody      string `long:"pr-body" desc:"Body of the pull request for the submission."`
+	Author      string `long:"author" desc:"Author of the submitted files."`
+	Source      string `long:"source" desc:"URL where the original files can be found."`
+	License     string `long:"license" desc:"SPDX license identifier of the submitted files."`
+	Description string `long:"description" desc:"Description stored alongside each submitted file."`
+	OnExists    string `long:"on-exists" desc:"What to do if the keyset already exists: overwrite, append, or abort."`
+}
+
+// interactive reports whether the submission should prompt the user
+// for input or be driven entirely by flags.
+func (f *SubmitFlags) interactive() bool {
+	return !f.Yes &&
+		f.Application == "" &&
+		f.Category == "" &&
+		f.Filename == "" &&
+		f.Title == "" &&
+		f.Commit == "" &&
+		f.PRBody == "" &&
+		f.Author == "" &&
+		f.Source == "" &&
+		f.License == "" &&
+		f.Description == ""
 }
 
 // SubmitRun authenticates the user through our OAuth app and uses that to
@@ -60,6 +92,11 @@ func SubmitRun(r *cmd.Root, c *cmd.Sub) {
 
 	// Parse upload args
 	flags := c.Flags.(*SubmitFlags)
+	interactive := flags.interactive()
+
+	// Validate the existing keyset policy before doing any work.
+	onExists, err := existsPolicy(flags.OnExists)
+	checkErrorCode(rFlags, exitUsage, err)
 
 	// Check if .ark directory already exists.
 	info, err := os.Stat(".ark")
@@ -71,19 +108,22 @@ func SubmitRun(r *cmd.Root, c *cmd.Sub) {
 			"    ark init\n\n" +
 			"Before attempting to upload any files.\n",
 		)
-		os.Exit(1)
+		os.Exit(exitFailure)
 	}
 
-	// Open previous cache if exists
-	f, err := os.Open(AddedFilesPath)
-	if err != nil && os.IsNotExist(err) {
+	// Open the staging index.
+	index, err := stage.Read()
+	checkError(rFlags, err)
+
+	if index.Len() == 0 {
 		fmt.Println("No files are currently added, nothing to submit. Use")
 		fmt.Println("    ark add <files>...")
 		fmt.Println("to add files for submission.")
+		if !interactive {
+			os.Exit(exitUsage)
+		}
 		return
 	}
-	checkError(rFlags, err)
-	defer f.Close()
 
 	// Swap out an alias for the corresponding url
 	alias, ok := config.Global.Manifest.Aliases[args.Manifest]
@@ -95,79 +135,21 @@ func SubmitRun(r *cmd.Root, c *cmd.Sub) {
 	// |   Check Git Info   |
 	// +--------------------+
 
-	if config.Global.Git.Email == "" || config.Global.Git.Name == "" {
-		err = queryUserSaveGitInfo()
-		checkError(rFlags, err)
-
-		err = config.WriteFile(rFlags.Config, &config.Global)
-		checkError(rFlags, err)
-	}
+	checkGitIdentity(rFlags, interactive)
+	checkPublishFlags(rFlags, flags.IsPR, flags.Direct)
 
 	// +--------------------+
 	// |      Load Auth     |
 	// +--------------------+
 
-	if config.Global.Git.Token == "" {
-		// Give the user a chance to change the account they logged in with
-		// if it was incorrect.
-		correctUser := false
-		var guard upstream.Guard
-		for !correctUser {
-			// Launch upstream auth workflow if local Git Token is empty.
-			guard, err = manifest.Auth(args.Manifest)
-			if err != nil && err.Error() == "unknown upstream" {
-				fmt.Println("Error: Ark was unable to identify a known upstream")
-				fmt.Println("for your repository. Please use,")
-				fmt.Println("\t\"ark config git.token YOUR-VALUE\"")
-				fmt.Println("to set your git token manually before retrying")
-				fmt.Println("your submission without -p")
-			}
-			checkError(rFlags, err)
-
-			// Print out message to user about device code.
-			printAuthCode(guard.GetCode(), guard.GetExpireInterval())
-
-			// Begin polling process for authorization
-			interval := time.Duration(guard.GetInterval()) * time.Second
-			for {
-				wait(interval)
-				status, err := guard.CheckStatus()
-				checkError(rFlags, err)
-
-				if status == "slow_down" {
-					interval = interval + 5*time.Second
-					continue
-				}
-				if status != "authorization_pending" {
-					break
-				}
-			}
-			fmt.Print("\r")
-			username, err := guard.GetUser()
-			checkError(rFlags, err)
-
-			correctUser = queryUserCorrect(username)
-			fmt.Println()
-		}
-
-		// Save access information to internal memory
-		config.Global.Git.Username, _ = guard.GetUser()
-		config.Global.Git.Token = guard.GetAccessToken()
-
-		// Ask the user if they would like to save their git credentials
-		saveCreds := queryUserSaveCreds()
-		if saveCreds {
-			err = config.WriteFile(rFlags.Config, &config.Global)
-			checkError(rFlags, err)
-		}
-	}
+	loadAuth(rFlags, args.Manifest, interactive)
 
 	// +--------------------+
 	// |    Load Manifest   |
 	// +--------------------+
 
 	// Parse manifest url
-	urlPath, err := url.Parse(args.Manifest)
+	urlPath, err := manifest.ParseLocation(args.Manifest)
 	checkError(rFlags, err)
 
 	// Extract manifest name from url
@@ -180,12 +162,7 @@ func SubmitRun(r *cmd.Root, c *cmd.Sub) {
 	manifest, err := manifest.Init(
 		filepath.Join(manifestPath, "manifest"),
 		args.Manifest,
-		manifest.GitOptions{
-			Name:     config.Global.Git.Name,
-			Username: config.Global.Git.Username,
-			Email:    config.Global.Git.Email,
-			Token:    config.Global.Git.Token,
-		},
+		gitOptions(rFlags, args.Manifest, true),
 	)
 	checkError(rFlags, err)
 
@@ -194,7 +171,11 @@ func SubmitRun(r *cmd.Root, c *cmd.Sub) {
 	// +--------------------+
 	var app parser.Application
 	overwriteOpt := ""
-	for overwriteOpt != "o" && overwriteOpt != "a" {
+	if !interactive {
+		// Build the application from flags instead of an editor.
+		app, err = flagApplication(flags)
+		checkErrorCode(rFlags, exitUsage, err)
+	} else {
 		// Construct application path
 		appPath := filepath.Join(".ark", "commit")
 
@@ -224,25 +205,41 @@ func SubmitRun(r *cmd.Root, c *cmd.Sub) {
 
 		app, err = parser.ParseApplication(string(buf))
 		checkError(rFlags, err)
+	}
 
-		// Check for existing file
-		prevPath := filepath.Join(
-			config.Global.Manifest.Path,
-			manifestName,
-			"manifest",
-			app.Category,
-			app.Filename,
-		)
-		_, err = os.Stat(prevPath)
-		if err == nil {
+	// Check for existing file
+	categoryPath := filepath.Join(manifestPath, "manifest", app.Category)
+	_, err = os.Stat(filepath.Join(categoryPath, app.Filename))
+	if err == nil {
+		overwriteOpt = onExists
+		if interactive && flags.OnExists == "" {
 			overwriteOpt = queryUserAppendFile(
 				filepath.Join(app.Category, app.Filename),
 			)
-			continue
 		}
-		overwriteOpt = "o"
+
+		switch overwriteOpt {
+		case "o", "a":
+		case "r":
+			// Find a new name for the keyset that doesn't collide.
+			app, err = renameApplication(app, categoryPath, interactive)
+			checkErrorCode(rFlags, exitUsage, err)
+			fmt.Printf("Submitting as %v\n", filepath.Join(app.Category, app.Filename))
+			overwriteOpt = "o"
+		default:
+			fmt.Printf("A file already exists at %v in the repo.\n",
+				filepath.Join(app.Category, app.Filename))
+			if !interactive {
+				fmt.Println("Use --on-exists with overwrite, append, or rename to continue.")
+			}
+			fmt.Println("Submission aborted.")
+			os.Exit(exitConflict)
+		}
 	}
 
+	err = checkNewNames(app, filepath.Join(manifestPath, "manifest"))
+	checkErrorCode(rFlags, exitUsage, err)
+
 	// +--------------------+
 	// |   Load IPFS Node   |
 	// +--------------------+
@@ -261,28 +258,9 @@ func SubmitRun(r *cmd.Root, c *cmd.Sub) {
 	// | Generate Manifest  |
 	// +--------------------+
 
-	// Count the number of files in the cache
-	numFiles, err := lineCounter(f)
-	checkError(rFlags, err)
-
-	_, err = f.Seek(0, 0)
-	checkError(rFlags, err)
-
-	// In order to not copy files to ~/.ark/ipfs/
-	// we need to create a workdir symlink in .ark
-	wd, err := os.Getwd()
-	checkError(rFlags, err)
-
-	link := filepath.Join(config.Global.Manifest.Path, manifestName, "workdir")
-	err = os.Symlink(wd, link)
-	if err != nil && os.IsExist(err) {
-		os.Remove(link)
-		err = os.Symlink(wd, link)
-	}
-	checkError(rFlags, err)
-
-	// Create manifest map
-	files := make(map[string]string, numFiles)
+	// Create manifest map keyed by path and header
+	files := make(map[string]keyset.Entry, index.Len())
+	header := keyset.Header{}
 
 	if overwriteOpt == "a" {
 		// Check for existing file
@@ -293,152 +271,512 @@ func SubmitRun(r *cmd.Root, c *cmd.Sub) {
 			app.Category,
 			app.Filename,
 		)
-		prev, err := os.Open(prevPath)
-		if err == nil {
-			scanner := bufio.NewScanner(prev)
-			for scanner.Scan() {
-				data := strings.Fields(scanner.Text())
-				files[data[0]] = data[1]
+		prev, err := keyset.ReadFile(prevPath)
+		if err != nil && !os.IsNotExist(err) {
+			checkError(rFlags, err)
+		}
+		if prev != nil {
+			header = prev.Header
+			for _, entry := range prev.Entries {
+				files[entry.Path] = entry
 			}
 		}
-		prev.Close()
 	}
 
 	fmt.Println("Building Manifest...")
 
-	// Generate progress bar
-	ipfsBar := progressbar.Default(int64(numFiles))
-	ipfsBar.RenderBlank()
+	// Hash any files that changed since they were staged.
+	err = hashStaged(ipfs, index, ".", index.Paths())
+	if err != nil {
+		index.Write()
+	}
+	checkError(rFlags, err)
 
-	// Add files to internal ipfs node
-	scanner := bufio.NewScanner(f)
-	for scanner.Scan() {
-		cid, err := ipfs.Add(filepath.Join(link, scanner.Text()), true)
-		checkError(rFlags, err)
+	err = index.Write()
+	checkError(rFlags, err)
+
+	// Add files to map along with what we know about them, replacing
+	// any previous entry for the same path.
+	for _, path := range index.Paths() {
+		staged := index.Entries[path]
+		entry := keyset.Entry{
+			CID:  staged.CID,
+			Path: path,
+			Metadata: keyset.Metadata{
+				Size:        staged.Size,
+				Type:        staged.Type,
+				License:     app.License,
+				Description: app.Description,
+			},
+		}
+		if prev, ok := files[path]; ok {
+			if entry.License == "" {
+				entry.License = prev.License
+			}
+			if entry.Description == "" {
+				entry.Description = prev.Description
+			}
+		}
+		files[path] = entry
+	}
 
-		// Add file to map.
-		files[cid] = scanner.Text()
-		ipfsBar.Add(1)
+	// Fill in the header from the application, keeping any
+	// previous values the application leaves empty.
+	if app.Title != "" {
+		header.Title = app.Title
+	}
+	if app.Author != "" {
+		header.Author = app.Author
+	}
+	if app.Source != "" {
+		header.Source = app.Source
 	}
 
-	// Remove Symlink to Working Directory
-	err = os.Remove(link)
+	// Generate manifest content from map.
+	ks := &keyset.Keyset{Header: header}
+	for _, entry := range files {
+		ks.Entries = append(ks.Entries, entry)
+	}
+	out, duplicates, err := manifest.Generate(ks)
 	checkError(rFlags, err)
 
-	// Construct destination manifest path
-	manPath := filepath.Join(
-		config.Global.Manifest.Path,
-		manifestName,
-		"manifest",
-		app.Category,
-		app.Filename,
-	)
+	// Let the user know about identical files listed more than once.
+	for _, duplicate := range duplicates {
+		fmt.Printf("Warning: %d files share the CID %s:\n", len(duplicate.Paths), duplicate.CID)
+		for _, path := range duplicate.Paths {
+			fmt.Printf("\t%s\n", path)
+		}
+	}
 
 	// +--------------------+
 	// |  Upload Manifest   |
 	// +--------------------+
-	// Add place holders for PRs to use branches.
-	var mainBranchName string
-	var newBranchName string
 
-	// Check if we should push direct to
-	// the git repository or attempt to create a pull request.
-	haveWrite, err := manifest.HaveWriteAccess()
-	if err != nil && err.Error() != "unknown upstream" {
+	keysetPath := path.Join(filepath.ToSlash(app.Category), app.Filename)
+	pr := publishKeyset(rFlags, manifest, publication{
+		Path: filepath.Join(
+			config.Global.Manifest.Path,
+			manifestName,
+			"manifest",
+			app.Category,
+			app.Filename,
+		),
+		Branch: manifest.SubmissionBranch("submit", keysetPath),
+		Commit: app.Commit,
+		Title:  app.Title,
+		Body:   app.PRBody,
+		IsPR:   flags.IsPR,
+		Direct: flags.Direct,
+	}, generated(out))
+
+	recordSubmission(rFlags, args.Manifest, keysetPath, app.Title, pr)
+
+	fmt.Println("Completed Submission Successfully!")
+	printFollowPR(pr)
+	os.Remove(filepath.Join(".ark", "commit"))
+}
+
+// checkGitIdentity makes sure a git name and email are configured,
+// asking for them when the command is interactive.
+func checkGitIdentity(rFlags *GlobalFlags, interactive bool) {
+	if config.Global.Git.Email == "" || config.Global.Git.Name == "" {
+		if !interactive {
+			fmt.Println("Error: Ark does not have a git identity saved. Please use,")
+			fmt.Println("\t\"ark config git.name YOUR-NAME\"")
+			fmt.Println("\t\"ark config git.email YOUR-EMAIL\"")
+			fmt.Println("to set your identity before retrying your submission.")
+			os.Exit(exitUsage)
+		}
+		err := queryUserSaveGitInfo()
+		checkError(rFlags, err)
+
+		err = config.WriteFile(rFlags.Config, &config.Global)
 		checkError(rFlags, err)
 	}
+}
 
-	if !haveWrite || flags.IsPR {
-		// Force status to a PR if we don't have
-		// write access to the repository.
-		flags.IsPR = true
+// loadAuth makes sure a valid git token is available for the manifest's
+// upstream before any long running work begins, walking the user through
+// logging in when the command is interactive.
+func loadAuth(rFlags *GlobalFlags, location string, interactive bool) {
+	if !manifest.NeedsToken(location) {
+		return
+	}
 
-		// Setup a repository fork when creating a PR.
-		err = manifest.Fork()
-		checkError(rFlags, err)
+	token := gitOptions(rFlags, location, true).Token
+	if token != "" {
+		// Make sure the token hasn't expired or been revoked.
+		info, err := manifest.CheckToken(location, token)
+		if err == nil {
+			checkError(rFlags, migrateToken(rFlags, location, info.User))
+			return
+		}
+		if err.Error() == "unknown upstream" {
+			return
+		}
+		fmt.Printf("Error: The git token for %s was rejected: %v\n", credentialHost(location), err)
+	}
 
-		// Store main git branch name
-		mainBranchName, err = manifest.GetBranchName()
+	if !interactive {
+		fmt.Println("Error: Ark does not have a valid git token saved. Please use,")
+		fmt.Printf("\t\"ark auth login %s\"\n", location)
+		fmt.Println("or set ARK_GIT_TOKEN before retrying your submission.")
+		os.Exit(exitAuth)
+	}
+
+	cred := login(rFlags, location)
+
+	// Keep the token in memory for the rest of the command.
+	config.Global.Git.Username = cred.Username
+	config.Global.Git.Token = cred.Token
+
+	// Ask the user if they would like to save their git credentials
+	if queryUserSaveCreds() {
+		err := credentialStore(rFlags).Store(cred)
 		checkError(rFlags, err)
+	}
+}
 
-		// Construct a new branch name
-		newBranchName = "submit/" + app.Filename
+// publication describes a change to a keyset within a manifest.
+type publication struct {
+	Path   string
+	Branch string
+	Commit string
+	Title  string
+	Body   string
+	IsPR   bool
+	Direct bool
+}
 
-		// Pull an existing branch to update if possible.
-		err = manifest.PullBranch(newBranchName)
-		if err != nil {
-			if err.Error() == "branch not found" {
-				err = manifest.CreateBranch(newBranchName)
-			}
+// checkPublishFlags makes sure a change isn't both pushed directly
+// and submitted as a pull request.
+func checkPublishFlags(rFlags *GlobalFlags, isPR, direct bool) {
+	if isPR && direct {
+		checkErrorCode(rFlags, exitUsage, errors.New("--pull-request and --direct can't be used together"))
+	}
+}
+
+// publishKeyset writes a keyset into the local copy of a manifest and pushes
+// it to the repository, or to a branch of a fork with a pull request if the
+// user asked for one or doesn't have write access. Pushes to a plain git
+// server that are rejected are submitted for review instead, unless the
+// user asked to push directly. Empty content removes the keyset instead,
+// and its metadata file is written or removed alongside it. The content is
+// generated once the branch the change is made on is checked out, so
+// changes already pending on it are built upon. It returns the pull
+// request, or nil if the change was pushed directly to the repository.
+func publishKeyset(rFlags *GlobalFlags, m *manifest.Manifest, pub publication, generate func() (manifest.Generated, error)) *upstream.PullRequest {
+	// Check if we should push direct to
+	// the git repository or attempt to create a pull request.
+	direct := pub.Direct
+	if !direct && !pub.IsPR {
+		// Manifests on unknown hosts are pushed to directly.
+		haveWrite, err := m.HaveWriteAccess()
+		if err != nil && err.Error() != "unknown upstream" {
 			checkError(rFlags, err)
 		}
+		direct = haveWrite
+	}
+
+	if direct {
+		err := commitKeyset(m, pub, generate)
+		checkError(rFlags, err)
 
-		err = manifest.SwitchBranch(newBranchName)
+		err = m.Push()
+		if err == nil {
+			return nil
+		}
+		if pub.Direct || !m.RecordsSubmissions() {
+			checkError(rFlags, err)
+		}
+		fmt.Printf("Pushing to the manifest failed: %v\n", err)
+		fmt.Println("Submitting the change for review instead.")
+		err = m.ResetToOrigin()
 		checkError(rFlags, err)
 	}
 
-	// Make destination manifest path
-	err = os.MkdirAll(filepath.Dir(manPath), os.ModePerm)
+	// Setup a repository fork when creating a PR.
+	err := m.Fork()
+	if err != nil && err.Error() == "unknown upstream" {
+		fmt.Println("Error: Ark was unable to identify a known upstream")
+		fmt.Println("for your repository. If it's hosted on a plain git")
+		fmt.Println("server, add its host to the [upstreams] of your")
+		fmt.Println("config with the type \"git\", or push without -p.")
+		checkErrorCode(rFlags, exitUsage, err)
+	}
 	checkError(rFlags, err)
 
-	// Create manifest file
-	new, err := os.Create(manPath)
+	// Store main git branch name
+	mainBranchName, err := m.GetBranchName()
 	checkError(rFlags, err)
-	defer new.Close()
 
-	// Generate manifest content from map.
-	out, err := manifest.Generate(files)
+	// Pull an existing branch to update if possible.
+	err = m.PullBranch(pub.Branch)
+	if err != nil {
+		if err.Error() == "branch not found" {
+			err = m.CreateBranch(pub.Branch)
+		}
+		checkError(rFlags, err)
+	}
+
+	err = m.SwitchBranch(pub.Branch)
 	checkError(rFlags, err)
 
-	// Write manifest to file
-	_, err = new.WriteString(out)
+	err = commitKeyset(m, pub, generate)
 	checkError(rFlags, err)
 
-	// Close manifest file.
-	err = new.Close()
+	err = m.Push()
 	checkError(rFlags, err)
 
-	// Commit changes to repository.
-	err = manifest.Commit(manPath, app.Commit)
+	// Open a PR if one doesn't already exist.
+	pr, err := m.SearchPrByBranch(pub.Branch)
+	if err != nil {
+		pr, err = m.OpenPR(mainBranchName, pub.Title, pub.Body)
+		checkError(rFlags, err)
+	}
+
+	// Switch back to the main manifest branch
+	err = m.SwitchBranch(mainBranchName)
 	checkError(rFlags, err)
+	return &pr
+}
+
+// commitKeyset writes the generated content of a keyset into the current
+// branch of a manifest and commits it.
+func commitKeyset(m *manifest.Manifest, pub publication, generate func() (manifest.Generated, error)) error {
+	content, err := generate()
+	if err != nil {
+		return err
+	}
+
+	if content.Keyset == "" {
+		// Remove a keyset that no longer has any entries.
+		err = os.Remove(pub.Path)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	} else {
+		// Make destination manifest path
+		err = os.MkdirAll(filepath.Dir(pub.Path), os.ModePerm)
+		if err != nil {
+			return err
+		}
+
+		// Write manifest to file
+		err = os.WriteFile(pub.Path, []byte(content.Keyset), 0644)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Keep the keyset's metadata file alongside it.
+	if content.Meta == "" {
+		err = os.Remove(pub.Path + keyset.MetaSuffix)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	} else {
+		err = os.WriteFile(pub.Path+keyset.MetaSuffix, []byte(content.Meta), 0644)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Commit changes to repository.
+	return m.Commit(pub.Path, pub.Commit)
+}
+
+// generated returns content for publishKeyset that was generated before
+// checking out the branch it's published to.
+func generated(content manifest.Generated) func() (manifest.Generated, error) {
+	return func() (manifest.Generated, error) {
+		return content, nil
+	}
+}
 
-	// Push changes to repository.
-	err = manifest.Push()
+// recordSubmission records a change published to a keyset so "ark
+// submissions" can follow its pull request. Changes are only recorded
+// from within an Ark repository.
+func recordSubmission(rFlags *GlobalFlags, location, keysetPath, title string, pr *upstream.PullRequest) {
+	info, err := os.Stat(".ark")
+	if err != nil || !info.IsDir() {
+		return
+	}
+
+	record := stage.Submission{
+		Manifest: location,
+		Keyset:   keysetPath,
+		Title:    title,
+		State:    stage.StatePushed,
+		Created:  time.Now().UTC(),
+	}
+	if pr != nil {
+		record.Branch = pr.Branch
+		record.Number = pr.Number
+		record.URL = pr.URL
+		record.State = upstream.PrOpen
+	}
+	err = stage.RecordSubmission(record)
 	checkError(rFlags, err)
+}
 
-	// Open a PR if one doesn't already exist.
-	if flags.IsPR {
-		err := manifest.SearchPrByBranch(newBranchName)
+// printFollowPR tells the user where to follow a pull request.
+func printFollowPR(pr *upstream.PullRequest) {
+	if pr != nil && pr.URL != "" {
+		fmt.Printf("Follow your pull request at %s\n", pr.URL)
+	}
+}
+
+// existsPolicy converts the value of the --on-exists flag into the
+// option letters used by queryUserAppendFile. An empty result means
+// the submission should be aborted if the keyset already exists.
+func existsPolicy(policy string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(policy)) {
+	case "o", "overwrite":
+		return "o", nil
+	case "a", "append":
+		return "a", nil
+	case "r", "rename":
+		return "r", nil
+	case "", "abort":
+		return "", nil
+	}
+	return "", fmt.Errorf("unknown --on-exists value %q, expected overwrite, append, rename, or abort", policy)
+}
+
+// flagApplication builds a submission application from the --application
+// file and the individual field flags, which take precedence over the file.
+func flagApplication(flags *SubmitFlags) (app parser.Application, err error) {
+	if flags.Application != "" {
+		buf, err := os.ReadFile(flags.Application)
 		if err != nil {
-			err = manifest.OpenPR(mainBranchName, app.Title, app.PRBody)
-			checkError(rFlags, err)
+			return app, err
 		}
+		app = parser.ReadApplication(string(buf))
+	}
 
-		// Switch back to the main manifest branch
-		err = manifest.SwitchBranch(mainBranchName)
-		checkError(rFlags, err)
+	if flags.Category != "" {
+		app.Category = flags.Category
+	}
+	if flags.Filename != "" {
+		app.Filename = flags.Filename
+	}
+	if flags.Title != "" {
+		app.Title = flags.Title
+	}
+	if flags.Commit != "" {
+		app.Commit = flags.Commit
+	}
+	if flags.PRBody != "" {
+		app.PRBody = flags.PRBody
+	}
+	if flags.Author != "" {
+		app.Author = flags.Author
+	}
+	if flags.Source != "" {
+		app.Source = flags.Source
+	}
+	if flags.License != "" {
+		app.License = flags.License
+	}
+	if flags.Description != "" {
+		app.Description = flags.Description
 	}
 
-	fmt.Println("Completed Submission Successfully!")
-	f.Close()
-	os.Remove(filepath.Join(".ark", "commit"))
+	err = app.Validate()
+	return app, err
+}
+
+// renameApplication gives an application a keyset filename that doesn't
+// collide with an existing keyset in the same category. Interactive users
+// may type their own name, otherwise the first free name is used.
+func renameApplication(app parser.Application, categoryPath string, interactive bool) (parser.Application, error) {
+	base := strings.TrimSuffix(app.Filename, ".ks")
+	for {
+		// Propose the next available numbered filename.
+		proposed := ""
+		for i := 1; proposed == ""; i++ {
+			name := fmt.Sprintf("%s-%d.ks", base, i)
+			_, err := os.Stat(filepath.Join(categoryPath, name))
+			if os.IsNotExist(err) {
+				proposed = name
+			} else if err != nil {
+				return app, err
+			}
+		}
+
+		renamed := app
+		renamed.Filename = proposed
+		if interactive {
+			renamed.Filename = queryUserRename(proposed)
+		}
+
+		// Re-validate the application with its new filename.
+		err := renamed.Validate()
+		if err == nil {
+			err = keyset.CheckName("keyset", strings.TrimSuffix(renamed.Filename, ".ks"))
+		}
+		if err != nil {
+			if !interactive {
+				return app, err
+			}
+			fmt.Println(err)
+			continue
+		}
+
+		_, err = os.Stat(filepath.Join(categoryPath, renamed.Filename))
+		if err == nil {
+			fmt.Printf("A file already exists at %v in the repo.\n",
+				filepath.Join(renamed.Category, renamed.Filename))
+			continue
+		}
+		if !os.IsNotExist(err) {
+			return app, err
+		}
+		return renamed, nil
+	}
+}
+
+// checkNewNames applies the naming rules for manifests to the categories
+// and keyset a submission creates. Existing ones are left to "ark lint",
+// so they can still be appended to or overwritten.
+func checkNewNames(app parser.Application, root string) error {
+	dir := root
+	if app.Category != "." {
+		for _, name := range strings.Split(filepath.ToSlash(app.Category), "/") {
+			dir = filepath.Join(dir, name)
+			_, err := os.Stat(dir)
+			if os.IsNotExist(err) {
+				err = keyset.CheckName("category", name)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err := os.Stat(filepath.Join(dir, app.Filename))
+	if os.IsNotExist(err) {
+		return keyset.CheckName("keyset", strings.TrimSuffix(app.Filename, ".ks"))
+	}
+	return err
 }
 
 // printAuthCode prints the user's code in a pretty format.
-func printAuthCode(code string, expiry int) {
+func printAuthCode(verificationURL, code string, expiry int) {
 	now := time.Now()
 	expireTime := now.Add(time.Duration(expiry) * time.Second)
 	minutes := math.Round(float64(expiry) / 60.0)
 	fmt.Printf(
-		`Go to https://github.com/login/device and enter the following code. You should
-see a request to authorize "Ark GitHub Worker". Please authorize this request, but 
-not if it's from anyone other than Ark GitHub Worker by Arken!
+		`Go to %v and enter the following code. You should
+see a request to authorize Ark. Please authorize this request, but 
+not if it's from anyone other than Ark by Arken!
 =================================================================
                             %v
 =================================================================
 This code will expire in about %v minutes at %v.
 
-`, code, int(minutes), expireTime.Format("3:04 PM"))
+`, verificationURL, code, int(minutes), expireTime.Format("3:04 PM"))
 }
 
 // wait prints a pretty little animation while Ark waits for the user's to
@@ -467,6 +805,21 @@ func queryUserCorrect(user string) bool {
 	return input != "n" && input != "no"
 }
 
+func queryUserToken(tokenURL string) string {
+	fmt.Printf("Create an access token with permission to fork repositories\n"+
+		"and open pull requests at,\n\n    %v\n\nthen paste it here: ", tokenURL)
+
+	// Don't echo the token when reading from a terminal.
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		input, _ := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Println()
+		return strings.TrimSpace(string(input))
+	}
+	reader := bufio.NewReader(os.Stdin)
+	input, _ := reader.ReadString('\n')
+	return strings.TrimSpace(input)
+}
+
 func queryUserSaveCreds() bool {
 	fmt.Print("\nWould you like to save your access token for future submissions? (y/[n]) ")
 	reader := bufio.NewReader(os.Stdin)
@@ -487,6 +840,18 @@ func queryUserAppendFile(filePath string) string {
 	return input
 }
 
+func queryUserRename(proposed string) string {
+	fmt.Printf("Please enter a new name for your keyset [%v]: ", proposed)
+	reader := bufio.NewReader(os.Stdin)
+	input, _ := reader.ReadString('\n')
+	input = strings.TrimSpace(input)
+
+	if input == "" {
+		return proposed
+	}
+	return input
+}
+
 func queryUserSaveGitInfo() error {
 	reader := bufio.NewReader(os.Stdin)
 	fmt.Print("You don't appear to have an identity saved.\n" +
@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/arken/ark/parser"
+)
+
+func TestCheckNewNames(t *testing.T) {
+	root := t.TempDir()
+	err := os.MkdirAll(filepath.Join(root, "Old Docs"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(root, "Old Docs", "My Set.ks"), nil, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		category, filename string
+		ok                 bool
+	}{
+		// Existing categories and keysets can still be changed.
+		{"Old Docs", "My Set.ks", true},
+		{"Old Docs", "new-set.ks", true},
+		{"Old Docs/guides", "set.ks", true},
+		{".", "set.ks", true},
+		// New ones must follow the naming rules.
+		{"Old Docs", "New Set.ks", false},
+		{"Old Docs/New Guides", "set.ks", false},
+		{"New Docs", "set.ks", false},
+		{".", "Set.ks", false},
+	}
+	for _, test := range tests {
+		app := parser.Application{Category: test.category, Filename: test.filename}
+		err := checkNewNames(app, root)
+		if (err == nil) != test.ok {
+			t.Errorf("checkNewNames(%q, %q) = %v, want ok %v", test.category, test.filename, err, test.ok)
+		}
+	}
+}
@@ -1,9 +1,7 @@
 package cli
 
 import (
-	"bufio"
 	"fmt"
-	"net/url"
 	"os"
 	"path/filepath"
 	"time"
@@ -12,6 +10,7 @@ import (
 	"github.com/arken/ark/config"
 	"github.com/arken/ark/ipfs"
 	"github.com/arken/ark/manifest"
+	"github.com/arken/ark/stage"
 	"github.com/schollz/progressbar/v3"
 )
 
@@ -66,7 +65,7 @@ func UploadRun(r *cmd.Root, c *cmd.Sub) {
 	}
 
 	// Parse manifest url
-	urlPath, err := url.Parse(args.Manifest)
+	urlPath, err := manifest.ParseLocation(args.Manifest)
 	checkError(rFlags, err)
 
 	// Extract manifest name from url
@@ -79,7 +78,7 @@ func UploadRun(r *cmd.Root, c *cmd.Sub) {
 	manifest, err := manifest.Init(
 		filepath.Join(manifestPath, "manifest"),
 		args.Manifest,
-		manifest.GitOptions{},
+		gitOptions(rFlags, args.Manifest, false),
 	)
 	checkError(rFlags, err)
 
@@ -95,22 +94,18 @@ func UploadRun(r *cmd.Root, c *cmd.Sub) {
 	)
 	checkError(rFlags, err)
 
-	// Open previous cache if exists
-	f, err := os.Open(AddedFilesPath)
-	if err != nil && os.IsNotExist(err) {
+	// Open the staging index.
+	index, err := stage.Read()
+	checkError(rFlags, err)
+
+	if index.Len() == 0 {
 		fmt.Println(0, "file(s) currently staged for submission & upload")
 		fmt.Println("Are you in the correct directory?")
 		return
 	}
-	checkError(rFlags, err)
-	defer f.Close()
 
 	// Count the number of files in the manifest
-	numFiles, err := lineCounter(f)
-	checkError(rFlags, err)
-
-	_, err = f.Seek(0, 0)
-	checkError(rFlags, err)
+	numFiles := index.Len()
 
 	// In order to not copy files to ~/.ark/ipfs/
 	// we need to create a workdir symlink in .ark
@@ -129,9 +124,8 @@ func UploadRun(r *cmd.Root, c *cmd.Sub) {
 
 	// Add files to internal ipfs node
 	go func() {
-		scanner := bufio.NewScanner(f)
-		for scanner.Scan() {
-			cid, err := ipfs.Add(filepath.Join(link, scanner.Text()), false)
+		for _, path := range index.Paths() {
+			cid, err := ipfs.Add(filepath.Join(link, path), false)
 			checkError(rFlags, err)
 
 			input <- cid
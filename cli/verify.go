@@ -0,0 +1,99 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/DataDrake/cli-ng/v2/cmd"
+	"github.com/arken/ark/ipfs"
+	"github.com/arken/ark/manifest"
+)
+
+func init() {
+	cmd.Register(&Verify)
+}
+
+// Verify checks a local copy of files against a manifest.
+var Verify = cmd.Sub{
+	Name:  "verify",
+	Alias: "vf",
+	Short: "Check a local copy of pulled files against a manifest.",
+	Args:  &VerifyArgs{},
+	Run:   VerifyRun,
+}
+
+// VerifyArgs handles the specific arguments for the verify command.
+type VerifyArgs struct {
+	Manifest string
+	Dir      string
+	Targets  []string `zero:"true"`
+}
+
+// VerifyRun re-hashes the files within a directory and compares them to the
+// CIDs recorded within a manifest. When keysets or categories are given all of
+// their entries must be present, otherwise only the files found are checked.
+func VerifyRun(r *cmd.Root, c *cmd.Sub) {
+	// Setup main application config.
+	rFlags := rootInit(r)
+
+	args := c.Args.(*VerifyArgs)
+
+	// Initialize Manifest
+	m, _, err := openManifest(args.Manifest, gitOptions(rFlags, args.Manifest, false))
+	checkError(rFlags, err)
+
+	// Collect the entries to check.
+	entries := []manifest.Entry{}
+	if len(args.Targets) == 0 {
+		entries, err = m.Find(func(entry manifest.Entry) bool {
+			dest, err := pullDestination(args.Dir, entry.Path)
+			if err != nil {
+				return false
+			}
+			_, err = os.Stat(dest)
+			return err == nil
+		})
+		checkError(rFlags, err)
+	}
+	for _, target := range args.Targets {
+		found, err := m.Resolve(target)
+		checkError(rFlags, err)
+		entries = append(entries, found...)
+	}
+
+	if len(entries) == 0 {
+		fmt.Println("No files from the manifest were found in", args.Dir)
+		os.Exit(exitFailure)
+	}
+
+	// Hashing doesn't need to connect to the cluster.
+	node, err := ipfs.CreateOfflineNode()
+	checkError(rFlags, err)
+
+	numValid, numInvalid, numMissing := 0, 0, 0
+	for _, entry := range entries {
+		dest, err := pullDestination(args.Dir, entry.Path)
+		checkError(rFlags, err)
+
+		if _, err = os.Stat(dest); os.IsNotExist(err) {
+			fmt.Printf("\tmissing:  %s\n", filepath.Join(args.Dir, entry.Path))
+			numMissing++
+			continue
+		}
+
+		valid, err := node.Verify(dest, entry.CID)
+		checkError(rFlags, err)
+		if !valid {
+			fmt.Printf("\tmismatch: %s (expected %s)\n", filepath.Join(args.Dir, entry.Path), entry.CID)
+			numInvalid++
+			continue
+		}
+		numValid++
+	}
+
+	fmt.Printf("%d file(s) verified, %d mismatched, %d missing\n", numValid, numInvalid, numMissing)
+	if numInvalid > 0 || numMissing > 0 {
+		os.Exit(exitFailure)
+	}
+}
@@ -18,9 +18,11 @@ var (
 )
 
 type Config struct {
-	Core     core     `toml:"core"`
-	Manifest manifest `toml:"manifest"`
-	Git      git      `toml:"git"`
+	Core        core                `toml:"core"`
+	Manifest    manifest            `toml:"manifest"`
+	Git         git                 `toml:"git"`
+	Credentials credentials         `toml:"credentials"`
+	Upstreams   map[string]upstream `toml:"upstreams"`
 }
 
 type core struct {
@@ -32,6 +34,14 @@ type git struct {
 	Username string `toml:"username"`
 	Email    string `toml:"email"`
 	Token    string `toml:"token"`
+	SSHKey   string `toml:"ssh_key,omitempty"`
+}
+
+// credentials configures where access tokens are stored. Helper is a
+// git credential helper, such as "osxkeychain" or "libsecret", and
+// tokens are stored in a file only readable by the user without one.
+type credentials struct {
+	Helper string `toml:"helper"`
 }
 
 type manifest struct {
@@ -39,6 +49,15 @@ type manifest struct {
 	Aliases map[string]string `toml:"aliases"`
 }
 
+// upstream maps a git host to the type of service it runs, for
+// self-hosted and enterprise instances Ark doesn't know about.
+type upstream struct {
+	Type     string `toml:"type"`
+	URL      string `toml:"url,omitempty"`
+	APIURL   string `toml:"api_url,omitempty"`
+	ClientID string `toml:"client_id,omitempty"`
+}
+
 func Init(path string) error {
 	// Generate the default config
 	Global = Config{
@@ -54,6 +73,7 @@ func Init(path string) error {
 			Email: "",
 			Token: "",
 		},
+		Upstreams: make(map[string]upstream),
 	}
 
 	// Setup default alias for core-manifest
@@ -65,15 +85,15 @@ func Init(path string) error {
 		return err
 	}
 
-	// Read in config from environment
-	err = sourceEnv(&Global)
+	// Write config file before reading the environment,
+	// so values such as tokens set there aren't saved.
+	err = WriteFile(path, &Global)
 	if err != nil {
 		return err
 	}
 
-	// Write config file
-	err = WriteFile(path, &Global)
-	return err
+	// Read in config from environment
+	return sourceEnv(&Global)
 }
 
 // ParseFile decodes the application configuration
@@ -88,9 +108,15 @@ func sourceEnv(in *Config) error {
 	// Check for env args matching each of the sub structs.
 	for i := 0; i < numSubStructs; i++ {
 		iter := reflect.ValueOf(in).Elem().Field(i)
+		if iter.Kind() != reflect.Struct {
+			continue
+		}
 		subStruct := strings.ToUpper(iter.Type().Name())
 		structType := iter.Type()
 		for j := 0; j < iter.NumField(); j++ {
+			if iter.Field(j).Kind() != reflect.String {
+				continue
+			}
 			fieldVal := iter.Field(j).String()
 			fieldName := structType.Field(j).Name
 			evName := "ARK" + "_" + subStruct + "_" + strings.ToUpper(fieldName)
@@ -104,20 +130,25 @@ func sourceEnv(in *Config) error {
 }
 
 // WriteFile writes changes to the application configuration back
-// to the TOML encoded file.
+// to the TOML encoded file, which only the user can read or write.
 func WriteFile(path string, in *Config) error {
 	buf := new(bytes.Buffer)
 	err := toml.NewEncoder(buf).Encode(in)
 	if err != nil {
 		return err
 	}
-	err = os.WriteFile(path, buf.Bytes(), os.ModePerm)
+	err = os.WriteFile(path, buf.Bytes(), 0600)
 	if os.IsNotExist(err) {
-		err = os.MkdirAll(filepath.Dir(path), os.ModePerm)
+		err = os.MkdirAll(filepath.Dir(path), 0700)
 		if err != nil {
 			return err
 		}
-		err = os.WriteFile(path, buf.Bytes(), os.ModePerm)
+		err = os.WriteFile(path, buf.Bytes(), 0600)
 	}
-	return err
+	if err != nil {
+		return err
+	}
+	// WriteFile only sets the mode of new files, so tighten
+	// the mode of files written by older versions of Ark.
+	return os.Chmod(path, 0600)
 }
@@ -0,0 +1,35 @@
+package credential
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrNotFound is returned when no credential is stored for a host.
+var ErrNotFound = errors.New("no credential stored")
+
+// Credential is the username and access token used for a git host.
+type Credential struct {
+	Host     string `toml:"-"`
+	Username string `toml:"username"`
+	Token    string `toml:"token"`
+}
+
+// Store saves the credentials for each git host.
+type Store interface {
+	// Get returns the credential stored for host, or ErrNotFound.
+	Get(host string) (Credential, error)
+	// Store saves a credential, replacing any stored for its host.
+	Store(cred Credential) error
+	// Erase removes the credential stored for host.
+	Erase(host string) error
+}
+
+// Open returns the credential helper named by helper if there is one,
+// otherwise a file store at path.
+func Open(helper, path string) Store {
+	if strings.TrimSpace(helper) != "" {
+		return NewHelperStore(strings.TrimSpace(helper))
+	}
+	return NewFileStore(path)
+}
@@ -0,0 +1,92 @@
+package credential
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/BurntSushi/toml"
+)
+
+// FileStore keeps credentials in a TOML file that only the
+// current user can read or write.
+type FileStore struct {
+	Path string
+}
+
+// NewFileStore creates a FileStore for the file at path.
+func NewFileStore(path string) *FileStore {
+	return &FileStore{Path: path}
+}
+
+func (s *FileStore) Get(host string) (Credential, error) {
+	creds, err := s.read()
+	if err != nil {
+		return Credential{}, err
+	}
+	cred, ok := creds[host]
+	if !ok {
+		return Credential{}, ErrNotFound
+	}
+	cred.Host = host
+	return cred, nil
+}
+
+func (s *FileStore) Store(cred Credential) error {
+	creds, err := s.read()
+	if err != nil {
+		return err
+	}
+	creds[cred.Host] = cred
+	return s.write(creds)
+}
+
+func (s *FileStore) Erase(host string) error {
+	creds, err := s.read()
+	if err != nil {
+		return err
+	}
+	if _, ok := creds[host]; !ok {
+		return ErrNotFound
+	}
+	delete(creds, host)
+	return s.write(creds)
+}
+
+// read decodes the credentials in the file, refusing to use a
+// file that other users can access.
+func (s *FileStore) read() (map[string]Credential, error) {
+	creds := make(map[string]Credential)
+	info, err := os.Stat(s.Path)
+	if os.IsNotExist(err) {
+		return creds, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if info.Mode().Perm()&0077 != 0 {
+		return nil, fmt.Errorf("%s is accessible by other users, please run \"chmod 600 %s\"", s.Path, s.Path)
+	}
+	_, err = toml.DecodeFile(s.Path, &creds)
+	return creds, err
+}
+
+// write encodes the credentials into the file.
+func (s *FileStore) write(creds map[string]Credential) error {
+	buf := new(bytes.Buffer)
+	err := toml.NewEncoder(buf).Encode(creds)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(s.Path), 0700)
+	if err != nil {
+		return err
+	}
+	err = os.WriteFile(s.Path, buf.Bytes(), 0600)
+	if err != nil {
+		return err
+	}
+	// WriteFile only sets the mode of new files.
+	return os.Chmod(s.Path, 0600)
+}
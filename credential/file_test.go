@@ -0,0 +1,80 @@
+package credential
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ark", "credentials.toml")
+	s := NewFileStore(path)
+
+	_, err := s.Get("github.com")
+	if err != ErrNotFound {
+		t.Fatalf("Get from a missing file = %v, want ErrNotFound", err)
+	}
+
+	cred := Credential{Host: "github.com", Username: "bob", Token: "secret"}
+	err = s.Store(cred)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("credentials file mode = %v, want 0600", info.Mode().Perm())
+	}
+
+	got, err := s.Get("github.com")
+	if err != nil || got != cred {
+		t.Errorf("Get = %+v, %v, want %+v", got, err, cred)
+	}
+
+	err = s.Erase("github.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.Get("github.com")
+	if err != ErrNotFound {
+		t.Errorf("Get after Erase = %v, want ErrNotFound", err)
+	}
+	err = s.Erase("github.com")
+	if err != ErrNotFound {
+		t.Errorf("Erase twice = %v, want ErrNotFound", err)
+	}
+}
+
+func TestFileStoreRefusesSharedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials.toml")
+	err := os.WriteFile(path, nil, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Simulate a umask that kept write access for the group.
+	err = os.Chmod(path, 0620)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewFileStore(path)
+	_, err = s.Get("github.com")
+	if err == nil {
+		t.Fatal("Get read a file other users can access")
+	}
+	err = s.Store(Credential{Host: "github.com", Token: "secret"})
+	if err == nil {
+		t.Fatal("Store wrote to a file other users can access")
+	}
+
+	err = os.Chmod(path, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Store(Credential{Host: "github.com", Token: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,117 @@
+package credential
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// HelperStore keeps credentials with a git credential helper, such as
+// "osxkeychain", "libsecret", or "manager", using the same protocol as
+// git. Helper is interpreted like git's credential.helper setting: a
+// name is run as "git credential-<name>", an absolute path as is, and
+// anything starting with "!" as a shell command. NoPrompt stops helpers
+// asking the user to log in, for lookups that can do without one.
+type HelperStore struct {
+	Helper   string
+	NoPrompt bool
+}
+
+// NewHelperStore creates a HelperStore for the named helper.
+func NewHelperStore(helper string) *HelperStore {
+	return &HelperStore{Helper: helper}
+}
+
+func (s *HelperStore) Get(host string) (Credential, error) {
+	out, err := s.run("get", Credential{Host: host})
+	if err != nil {
+		return Credential{}, err
+	}
+
+	cred := Credential{Host: host}
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		key, value, ok := cut(scanner.Text(), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "username":
+			cred.Username = value
+		case "password":
+			cred.Token = value
+		}
+	}
+	if cred.Token == "" {
+		return Credential{}, ErrNotFound
+	}
+	return cred, scanner.Err()
+}
+
+func (s *HelperStore) Store(cred Credential) error {
+	_, err := s.run("store", cred)
+	return err
+}
+
+func (s *HelperStore) Erase(host string) error {
+	_, err := s.run("erase", Credential{Host: host})
+	return err
+}
+
+// run sends a credential to the helper for an action
+// and returns the helper's output.
+func (s *HelperStore) run(action string, cred Credential) ([]byte, error) {
+	var c *exec.Cmd
+	fields := strings.Fields(s.Helper)
+	switch {
+	case len(fields) == 0:
+		return nil, errors.New("no credential helper set")
+	case strings.HasPrefix(s.Helper, "!"):
+		c = exec.Command("sh", "-c", s.Helper[1:]+" "+action)
+	case filepath.IsAbs(fields[0]):
+		c = exec.Command(fields[0], append(fields[1:], action)...)
+	default:
+		args := append([]string{"credential-" + fields[0]}, fields[1:]...)
+		c = exec.Command("git", append(args, action)...)
+	}
+
+	if s.NoPrompt {
+		c.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0", "GCM_INTERACTIVE=never")
+	}
+
+	input := &bytes.Buffer{}
+	fmt.Fprintf(input, "protocol=https\nhost=%s\n", cred.Host)
+	if cred.Username != "" {
+		fmt.Fprintf(input, "username=%s\n", cred.Username)
+	}
+	if cred.Token != "" {
+		fmt.Fprintf(input, "password=%s\n", cred.Token)
+	}
+	input.WriteString("\n")
+	c.Stdin = input
+
+	stderr := &bytes.Buffer{}
+	c.Stderr = stderr
+	out, err := c.Output()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if msg != "" {
+			return nil, fmt.Errorf("credential helper %s: %s", action, msg)
+		}
+		return nil, fmt.Errorf("credential helper %s: %w", action, err)
+	}
+	return out, nil
+}
+
+// cut slices s around the first instance of sep.
+func cut(s, sep string) (before, after string, found bool) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
@@ -0,0 +1,113 @@
+package credential
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fakeHelper writes a credential helper script that logs each action
+// and its input to a file, and answers "get" for github.com.
+func fakeHelper(t *testing.T) (script, log string) {
+	if runtime.GOOS == "windows" {
+		t.Skip("credential helper scripts need a POSIX shell")
+	}
+	t.Setenv("GIT_TERMINAL_PROMPT", "")
+	dir := t.TempDir()
+	script = filepath.Join(dir, "credential-fake")
+	log = filepath.Join(dir, "log")
+	err := os.WriteFile(script, []byte(`#!/bin/sh
+input=$(cat)
+printf '%s prompt=%s\n%s\n' "$1" "$GIT_TERMINAL_PROMPT" "$input" >> `+log+`
+case "$1" in
+get)
+	case "$input" in
+	*host=github.com*)
+		printf 'protocol=https\nhost=github.com\nusername=bob\npassword=secret\n'
+		;;
+	*host=broken.example*)
+		echo "fatal: keychain locked" >&2
+		exit 1
+		;;
+	esac
+	;;
+esac
+`), 0700)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return script, log
+}
+
+func readLog(t *testing.T, log string) string {
+	data, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Remove(log)
+	return string(data)
+}
+
+func TestHelperStoreGet(t *testing.T) {
+	script, log := fakeHelper(t)
+	s := NewHelperStore(script)
+
+	cred, err := s.Get("github.com")
+	want := Credential{Host: "github.com", Username: "bob", Token: "secret"}
+	if err != nil || cred != want {
+		t.Errorf("Get = %+v, %v, want %+v", cred, err, want)
+	}
+	if got := readLog(t, log); got != "get prompt=\nprotocol=https\nhost=github.com\n" {
+		t.Errorf("helper input = %q", got)
+	}
+
+	_, err = s.Get("gitlab.com")
+	if err != ErrNotFound {
+		t.Errorf("Get of an unknown host = %v, want ErrNotFound", err)
+	}
+
+	_, err = s.Get("broken.example")
+	if err == nil || !strings.Contains(err.Error(), "keychain locked") {
+		t.Errorf("Get from a failing helper = %v, want its message", err)
+	}
+}
+
+func TestHelperStoreNoPrompt(t *testing.T) {
+	script, log := fakeHelper(t)
+	s := NewHelperStore(script)
+	s.NoPrompt = true
+
+	_, err := s.Get("github.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := readLog(t, log); !strings.HasPrefix(got, "get prompt=0\n") {
+		t.Errorf("helper was run without GIT_TERMINAL_PROMPT=0: %q", got)
+	}
+}
+
+func TestHelperStoreStoreAndErase(t *testing.T) {
+	script, log := fakeHelper(t)
+
+	// A helper starting with "!" is run by the shell.
+	s := NewHelperStore("!" + script)
+	err := s.Store(Credential{Host: "github.com", Username: "bob", Token: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "store prompt=\nprotocol=https\nhost=github.com\nusername=bob\npassword=secret\n"
+	if got := readLog(t, log); got != want {
+		t.Errorf("helper input = %q, want %q", got, want)
+	}
+
+	err = s.Erase("github.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = "erase prompt=\nprotocol=https\nhost=github.com\n"
+	if got := readLog(t, log); got != want {
+		t.Errorf("helper input = %q, want %q", got, want)
+	}
+}
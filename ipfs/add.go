@@ -16,13 +16,7 @@ func (n *Node) Add(path string, onlyHash bool) (cid string, err error) {
 		}
 		return cid, err
 	}
-	output, err := n.api.Unixfs().Add(n.ctx, file, func(input *options.UnixfsAddSettings) error {
-		input.Pin = true
-		input.NoCopy = true
-		input.CidVersion = 1
-		input.OnlyHash = onlyHash
-		return nil
-	})
+	output, err := n.api.Unixfs().Add(n.ctx, file, addSettings(onlyHash))
 	if err != nil {
 		return cid, err
 	}
@@ -31,6 +25,33 @@ func (n *Node) Add(path string, onlyHash bool) (cid string, err error) {
 	return cid, nil
 }
 
+// Hash computes the identifier a file would be given by Add
+// without storing any of its contents.
+func (n *Node) Hash(path string) (cid string, err error) {
+	return n.Add(path, true)
+}
+
+// Verify reports whether the contents of a file match a CID.
+func (n *Node) Verify(path, cid string) (bool, error) {
+	result, err := n.Hash(path)
+	return result == cid, err
+}
+
+// addSettings returns the Unixfs settings used for every file Ark
+// adds so that hashes computed anywhere match the cluster's.
+func addSettings(onlyHash bool) options.UnixfsAddOption {
+	return func(input *options.UnixfsAddSettings) error {
+		input.Pin = true
+		// Only reference files from the filestore when storing them.
+		// CIDv1 always uses raw leaves so the CID is the same either way.
+		input.NoCopy = !onlyHash
+		input.CidVersion = 1
+		input.RawLeaves = true
+		input.OnlyHash = onlyHash
+		return nil
+	}
+}
+
 func getUnixfsNode(path string) (files.Node, error) {
 	st, err := os.Stat(path)
 	if err != nil {
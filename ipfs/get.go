@@ -1,6 +1,8 @@
 package ipfs
 
 import (
+	"errors"
+
 	files "github.com/ipfs/go-ipfs-files"
 	icorepath "github.com/ipfs/interface-go-ipfs-core/path"
 )
@@ -18,5 +20,9 @@ func (n *Node) Get(hash string) (files.File, error) {
 
 	// Convert node into file
 	file := files.ToFile(node)
+	if file == nil {
+		node.Close()
+		return nil, errors.New(hash + " is not a file")
+	}
 	return file, nil
 }
@@ -87,6 +87,27 @@ func CreateNode(repoPath string, args NodeConfArgs) (node *Node, err error) {
 
 }
 
+// CreateOfflineNode creates an in-memory IPFS node that isn't connected
+// to any peers. It can hash files but not share or retrieve them.
+func CreateOfflineNode() (node *Node, err error) {
+	// Initialize node structure
+	node = &Node{}
+	node.ctx, node.cancel = context.WithCancel(context.Background())
+
+	// Construct the node without a repository
+	node.node, err = core.NewNode(node.ctx, &core.BuildCfg{
+		Online:  false,
+		NilRepo: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Attach the Core API to the constructed node
+	node.api, err = coreapi.NewCoreAPI(node.node)
+	return node, err
+}
+
 func openFs(ctx context.Context, repoPath string) (result repo.Repo, err error) {
 	result, err = fsrepo.Open(repoPath)
 	if err != nil && err == fsrepo.ErrNeedMigration {
@@ -0,0 +1,388 @@
+package keyset
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ipfs/go-cid"
+)
+
+// Separator is written between the CID and path of an entry.
+const Separator string = "  "
+
+var (
+	// ErrMissingPath is returned for entries without a path.
+	ErrMissingPath = errors.New("missing path after CID")
+	// ErrInvalidCID is returned for entries whose CID can't be decoded.
+	ErrInvalidCID = errors.New("invalid CID")
+	// ErrBacktracking is returned for entries whose path leaves the
+	// keyset's directory.
+	ErrBacktracking = errors.New("path must stay within the keyset's directory")
+)
+
+// namePattern is the pattern every category and keyset name must match.
+var namePattern = regexp.MustCompile(`^[a-z0-9]+([._-][a-z0-9]+)*$`)
+
+// CheckName returns an error if name can't be used for a category, or
+// for a keyset once its ".ks" extension is removed. kind names what is
+// being checked in the error.
+func CheckName(kind, name string) error {
+	if namePattern.MatchString(name) {
+		return nil
+	}
+	return fmt.Errorf(
+		"%s name %q must only contain lowercase letters, numbers, and single \".\", \"-\" or \"_\" separators",
+		kind, name,
+	)
+}
+
+// Keyset is the header and entries of a keyset file.
+type Keyset struct {
+	Header
+	Entries []Entry
+}
+
+// Entry is a single file listed within a keyset.
+type Entry struct {
+	CID  string
+	Path string
+	Metadata
+}
+
+// ParseError describes a malformed line within a keyset.
+type ParseError struct {
+	Line int
+	Err  error
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Reader reads the entries of a keyset one at a time.
+type Reader struct {
+	scanner *bufio.Scanner
+	line    int
+	header  Header
+}
+
+// NewReader creates a Reader that reads a keyset from r.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{scanner: bufio.NewScanner(r)}
+}
+
+// Read returns the next entry of the keyset, skipping blank lines and
+// comments. Metadata lines, as written to metadata files, are attached
+// to the entry that follows them or to the keyset's header. Read returns io.EOF once every entry has
+// been read.
+func (r *Reader) Read() (Entry, error) {
+	meta := Metadata{}
+	for r.scanner.Scan() {
+		r.line++
+		line := r.scanner.Text()
+
+		key, value, ok := parseMeta(line)
+		if ok {
+			err := r.setMeta(&meta, key, value)
+			if err != nil {
+				return Entry{}, &ParseError{Line: r.line, Err: err}
+			}
+			continue
+		}
+
+		entry, ok, err := ParseLine(line)
+		if err != nil {
+			return entry, &ParseError{Line: r.line, Err: err}
+		}
+		if ok {
+			entry.Metadata = meta
+			return entry, nil
+		}
+	}
+	if err := r.scanner.Err(); err != nil {
+		return Entry{}, err
+	}
+	return Entry{}, io.EOF
+}
+
+// Line returns the line number of the most recently read entry.
+func (r *Reader) Line() int {
+	return r.line
+}
+
+// Header returns the keyset's header as read so far. The header is
+// only complete once Read has returned io.EOF.
+func (r *Reader) Header() Header {
+	return r.header
+}
+
+// setMeta stores a metadata value in either the header or the
+// metadata of the next entry.
+func (r *Reader) setMeta(meta *Metadata, key, value string) error {
+	if r.header.set(key, value) {
+		return nil
+	}
+	return meta.set(key, value)
+}
+
+// ReadAll reads the header and every entry of a keyset.
+func ReadAll(r io.Reader) (*Keyset, error) {
+	result := &Keyset{}
+	reader := NewReader(r)
+	for {
+		entry, err := reader.Read()
+		if err == io.EOF {
+			result.Header = reader.Header()
+			return result, nil
+		}
+		if err != nil {
+			return result, err
+		}
+		result.Entries = append(result.Entries, entry)
+	}
+}
+
+// ReadFile reads every entry of the keyset file at path, along with the
+// header and metadata from its metadata file if there is one. Metadata
+// for entries that are no longer listed in the keyset is dropped.
+func ReadFile(path string) (*Keyset, error) {
+	result, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	meta, err := readFile(path + MetaSuffix)
+	if os.IsNotExist(err) {
+		return result, nil
+	}
+	if err != nil {
+		return result, err
+	}
+	if result.Header.Empty() {
+		result.Header = meta.Header
+	}
+	metadata := make(map[Entry]Metadata, len(meta.Entries))
+	for _, entry := range meta.Entries {
+		metadata[Entry{CID: entry.CID, Path: entry.Path}] = entry.Metadata
+	}
+	for i, entry := range result.Entries {
+		if entry.Metadata.Empty() {
+			result.Entries[i].Metadata = metadata[Entry{CID: entry.CID, Path: entry.Path}]
+		}
+	}
+	return result, nil
+}
+
+// readFile reads the header and entries of a single file.
+func readFile(path string) (*Keyset, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadAll(f)
+}
+
+// ParseLine parses a single line of a keyset. Blank lines, comments
+// and metadata starting with "#" are reported as not containing an entry.
+func ParseLine(line string) (entry Entry, ok bool, err error) {
+	line = strings.TrimRight(line, "\r")
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+		return entry, false, nil
+	}
+
+	// The CID never contains whitespace, everything after it is the path.
+	i := strings.IndexAny(trimmed, " \t")
+	if i < 0 {
+		return entry, false, ErrMissingPath
+	}
+	entry.CID = trimmed[:i]
+	entry.Path = strings.TrimLeft(trimmed[i:], " \t")
+	return entry, true, nil
+}
+
+// Validate checks that an entry has a valid CID and a relative
+// path that doesn't backtrack out of its directory.
+func (e Entry) Validate() error {
+	if _, err := cid.Decode(e.CID); err != nil {
+		return fmt.Errorf("%w %q: %v", ErrInvalidCID, e.CID, err)
+	}
+	if e.Path == "" {
+		return errors.New("missing path after CID")
+	}
+	clean := filepath.ToSlash(filepath.Clean(e.Path))
+	if strings.HasPrefix(e.Path, "/") || clean == ".." || strings.HasPrefix(clean, "../") {
+		return fmt.Errorf("%w: %q", ErrBacktracking, e.Path)
+	}
+	return nil
+}
+
+// Sort orders entries by path and then by CID.
+func Sort(entries []Entry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Path != entries[j].Path {
+			return entries[i].Path < entries[j].Path
+		}
+		return entries[i].CID < entries[j].CID
+	})
+}
+
+// Write writes the entries of a keyset to w, sorted by path so that the
+// output is the same every time. Each line only holds a CID and a path so
+// that older versions of Ark can read it, the header and metadata are
+// written separately by WriteMeta.
+func Write(w io.Writer, k *Keyset) error {
+	buf := bufio.NewWriter(w)
+	for _, entry := range sorted(k.Entries) {
+		err := writeEntry(buf, entry)
+		if err != nil {
+			return err
+		}
+	}
+	return buf.Flush()
+}
+
+// WriteMeta writes the header of a keyset followed by the metadata of its
+// entries to w. Each entry's metadata is followed by the entry itself so
+// it can be matched back up with the keyset. Entries without metadata are
+// left out.
+func WriteMeta(w io.Writer, k *Keyset) error {
+	buf := bufio.NewWriter(w)
+	header := k.Header.lines()
+	if len(header) > 0 {
+		header = append(header, "")
+	}
+	for _, line := range header {
+		_, err := buf.WriteString(line + "\n")
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, entry := range sorted(k.Entries) {
+		lines := entry.Metadata.lines()
+		if len(lines) == 0 {
+			continue
+		}
+		for _, line := range lines {
+			_, err := buf.WriteString(line + "\n")
+			if err != nil {
+				return err
+			}
+		}
+		err := writeEntry(buf, entry)
+		if err != nil {
+			return err
+		}
+	}
+	return buf.Flush()
+}
+
+// HasMeta reports whether a keyset has a header or any entry metadata
+// to write with WriteMeta.
+func (k *Keyset) HasMeta() bool {
+	if !k.Header.Empty() {
+		return true
+	}
+	for _, entry := range k.Entries {
+		if !entry.Metadata.Empty() {
+			return true
+		}
+	}
+	return false
+}
+
+// sorted returns a copy of entries sorted by path.
+func sorted(entries []Entry) []Entry {
+	result := make([]Entry, len(entries))
+	copy(result, entries)
+	Sort(result)
+	return result
+}
+
+// writeEntry writes a single entry line.
+func writeEntry(buf *bufio.Writer, entry Entry) error {
+	if strings.ContainsAny(entry.CID, " \t\n") || strings.Contains(entry.Path, "\n") {
+		return fmt.Errorf("entry %q cannot be written to a keyset", entry.Path)
+	}
+	_, err := buf.WriteString(entry.CID + Separator + entry.Path + "\n")
+	return err
+}
+
+// WriteFile writes a keyset to the file at path, along with its header
+// and metadata to the metadata file next to it. The metadata file is
+// removed if the keyset has no header or metadata.
+func WriteFile(path string, k *Keyset) error {
+	err := writeFile(path, k, Write)
+	if err != nil {
+		return err
+	}
+	if !k.HasMeta() {
+		err = os.Remove(path + MetaSuffix)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	return writeFile(path+MetaSuffix, k, WriteMeta)
+}
+
+// writeFile creates the file at path and writes a keyset to it with write.
+func writeFile(path string, k *Keyset, write func(io.Writer, *Keyset) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	err = write(f, k)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Duplicate is a CID listed under more than one path.
+type Duplicate struct {
+	CID   string
+	Paths []string
+}
+
+// Dedupe removes entries repeating both the CID and path of an earlier
+// entry and reports the CIDs that are still listed under more than one
+// path, sorted by CID.
+func Dedupe(entries []Entry) (result []Entry, duplicates []Duplicate) {
+	seen := make(map[Entry]bool, len(entries))
+	paths := make(map[string][]string)
+	for _, entry := range entries {
+		key := Entry{CID: entry.CID, Path: entry.Path}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		paths[entry.CID] = append(paths[entry.CID], entry.Path)
+		result = append(result, entry)
+	}
+
+	for cid, list := range paths {
+		if len(list) > 1 {
+			sort.Strings(list)
+			duplicates = append(duplicates, Duplicate{CID: cid, Paths: list})
+		}
+	}
+	sort.Slice(duplicates, func(i, j int) bool {
+		return duplicates[i].CID < duplicates[j].CID
+	})
+	return result, duplicates
+}
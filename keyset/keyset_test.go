@@ -0,0 +1,224 @@
+package keyset
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const (
+	testCID  = "QmT78zSuBmuS4z925WZfrqQ1qHaJ56DQaTfyMUF7F8ff5o"
+	testCID2 = "QmXgm5QVTy8pRtKrTPmoWPGXNesehCpP4jjFMTpvGamc1p"
+)
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		line  string
+		entry Entry
+		ok    bool
+		err   error
+	}{
+		{line: "", ok: false},
+		{line: "   \t", ok: false},
+		{line: "# a comment", ok: false},
+		{line: "#: size 10", ok: false},
+		{line: testCID + "  a.txt", entry: Entry{CID: testCID, Path: "a.txt"}, ok: true},
+		{line: testCID + "\tdir/b c.txt\r", entry: Entry{CID: testCID, Path: "dir/b c.txt"}, ok: true},
+		{line: "  " + testCID + " a.txt  ", entry: Entry{CID: testCID, Path: "a.txt"}, ok: true},
+		{line: testCID, err: ErrMissingPath},
+	}
+	for _, test := range tests {
+		entry, ok, err := ParseLine(test.line)
+		if !errors.Is(err, test.err) {
+			t.Errorf("ParseLine(%q) error = %v, want %v", test.line, err, test.err)
+			continue
+		}
+		if ok != test.ok || entry != test.entry {
+			t.Errorf("ParseLine(%q) = %+v, %v, want %+v, %v", test.line, entry, ok, test.entry, test.ok)
+		}
+	}
+}
+
+func TestReader(t *testing.T) {
+	input := strings.Join([]string{
+		"#: title Example",
+		"#: author Someone",
+		"# a plain comment",
+		"",
+		"#: size 1024",
+		"#: license MIT",
+		testCID + "  a.txt",
+		testCID2,
+		"#: size -1",
+		testCID2 + "  b.txt",
+		"#: unknown ignored",
+		testCID2 + "  c.txt",
+	}, "\n")
+
+	reader := NewReader(strings.NewReader(input))
+
+	entry, err := reader.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Entry{CID: testCID, Path: "a.txt", Metadata: Metadata{Size: 1024, License: "MIT"}}
+	if entry != want {
+		t.Errorf("first entry = %+v, want %+v", entry, want)
+	}
+
+	// Malformed lines are reported with their line number, and reading
+	// carries on with the next line.
+	_, err = reader.Read()
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) || parseErr.Line != 8 || !errors.Is(err, ErrMissingPath) {
+		t.Errorf("second read error = %v, want a missing path on line 8", err)
+	}
+	_, err = reader.Read()
+	if !errors.As(err, &parseErr) || parseErr.Line != 9 {
+		t.Errorf("third read error = %v, want an invalid size on line 9", err)
+	}
+
+	entry, err = reader.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Path != "b.txt" || !entry.Metadata.Empty() {
+		t.Errorf("fourth entry = %+v, want b.txt without metadata", entry)
+	}
+	entry, err = reader.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if entry.Path != "c.txt" || reader.Line() != 12 {
+		t.Errorf("fifth entry = %+v on line %d, want c.txt on line 12", entry, reader.Line())
+	}
+
+	_, err = reader.Read()
+	if err != io.EOF {
+		t.Errorf("final read error = %v, want io.EOF", err)
+	}
+	header := Header{Title: "Example", Author: "Someone"}
+	if reader.Header() != header {
+		t.Errorf("header = %+v, want %+v", reader.Header(), header)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	k := &Keyset{
+		Header: Header{Title: "Example", Source: "https://example.org"},
+		Entries: []Entry{
+			{CID: testCID2, Path: "z.txt"},
+			{CID: testCID, Path: "a.txt", Metadata: Metadata{Size: 6, Description: "first\nfile"}},
+		},
+	}
+
+	buf := bytes.Buffer{}
+	err := Write(&buf, k)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Older versions of Ark read every line as a CID and a path, so
+	// the keyset must hold nothing else, sorted by path.
+	want := testCID + Separator + "a.txt\n" + testCID2 + Separator + "z.txt\n"
+	if buf.String() != want {
+		t.Errorf("Write output:\n%s\nwant:\n%s", buf.String(), want)
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		if len(strings.Fields(line)) != 2 || strings.HasPrefix(line, "#") {
+			t.Errorf("line %q can't be read by older versions of Ark", line)
+		}
+	}
+
+	buf.Reset()
+	err = WriteMeta(&buf, k)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = "#: title Example\n" +
+		"#: source https://example.org\n" +
+		"\n" +
+		"#: size 6\n" +
+		"#: description first file\n" +
+		testCID + Separator + "a.txt\n"
+	if buf.String() != want {
+		t.Errorf("WriteMeta output:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestWriteFileRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "example.ks")
+	k := &Keyset{
+		Header: Header{Title: "Example"},
+		Entries: []Entry{
+			{CID: testCID, Path: "a.txt", Metadata: Metadata{Size: 6, License: "MIT"}},
+			{CID: testCID2, Path: "z.txt"},
+		},
+	}
+	err := WriteFile(path, k)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	read, err := ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read, k) {
+		t.Errorf("read back %+v, want %+v", read, k)
+	}
+
+	// Metadata of entries no longer in the keyset is dropped.
+	err = os.WriteFile(path, []byte(testCID2+Separator+"z.txt\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	read, err = ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Keyset{Header: k.Header, Entries: []Entry{{CID: testCID2, Path: "z.txt"}}}
+	if !reflect.DeepEqual(read, want) {
+		t.Errorf("read back %+v, want %+v", read, want)
+	}
+
+	// The metadata file is removed once there's nothing left in it.
+	err = WriteFile(path, &Keyset{Entries: want.Entries})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path + MetaSuffix); !os.IsNotExist(err) {
+		t.Errorf("metadata file still exists: %v", err)
+	}
+}
+
+func TestWriteInvalidEntry(t *testing.T) {
+	k := &Keyset{Entries: []Entry{{CID: testCID, Path: "a\nb.txt"}}}
+	err := Write(io.Discard, k)
+	if err == nil {
+		t.Error("Write accepted a path containing a newline")
+	}
+}
+
+func TestDedupe(t *testing.T) {
+	entries := []Entry{
+		{CID: testCID, Path: "a.txt"},
+		{CID: testCID2, Path: "b.txt"},
+		{CID: testCID, Path: "a.txt", Metadata: Metadata{Size: 6}},
+		{CID: testCID, Path: "copy/a.txt"},
+	}
+
+	result, duplicates := Dedupe(entries)
+	if !reflect.DeepEqual(result, []Entry{entries[0], entries[1], entries[3]}) {
+		t.Errorf("Dedupe kept %+v", result)
+	}
+	want := []Duplicate{{CID: testCID, Paths: []string{"a.txt", "copy/a.txt"}}}
+	if !reflect.DeepEqual(duplicates, want) {
+		t.Errorf("Dedupe reported %+v, want %+v", duplicates, want)
+	}
+}
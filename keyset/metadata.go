@@ -0,0 +1,120 @@
+package keyset
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MetaPrefix starts a line of metadata within a keyset's metadata file.
+const MetaPrefix string = "#:"
+
+// MetaSuffix is appended to the path of a keyset to name the file holding
+// its header and the metadata of its entries. Older versions of Ark read
+// every line of a keyset as a CID and path, and panic on lines that aren't,
+// so metadata is kept out of the keyset itself in a file they never open.
+const MetaSuffix string = ".meta"
+
+// Header describes a keyset as a whole.
+type Header struct {
+	Title  string
+	Author string
+	Source string
+}
+
+// Metadata describes a single entry of a keyset. Every field is
+// optional and left empty when unknown.
+type Metadata struct {
+	Size        int64
+	Type        string
+	License     string
+	Description string
+}
+
+// Empty reports whether none of the header's fields are set.
+func (h Header) Empty() bool {
+	return h == Header{}
+}
+
+// Empty reports whether none of the metadata's fields are set.
+func (m Metadata) Empty() bool {
+	return m == Metadata{}
+}
+
+// set stores a header value and reports whether the key belongs to the header.
+func (h *Header) set(key, value string) bool {
+	switch key {
+	case "title":
+		h.Title = value
+	case "author":
+		h.Author = value
+	case "source":
+		h.Source = value
+	default:
+		return false
+	}
+	return true
+}
+
+// lines formats the header's fields as metadata lines.
+func (h Header) lines() (result []string) {
+	result = appendMeta(result, "title", h.Title)
+	result = appendMeta(result, "author", h.Author)
+	result = appendMeta(result, "source", h.Source)
+	return result
+}
+
+// set stores a metadata value. Unknown keys are ignored so that
+// keysets written by newer versions of Ark can still be read.
+func (m *Metadata) set(key, value string) error {
+	switch key {
+	case "size":
+		size, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || size < 0 {
+			return fmt.Errorf("invalid size %q", value)
+		}
+		m.Size = size
+	case "type":
+		m.Type = value
+	case "license":
+		m.License = value
+	case "description":
+		m.Description = value
+	}
+	return nil
+}
+
+// lines formats the metadata's fields as metadata lines.
+func (m Metadata) lines() (result []string) {
+	if m.Size > 0 {
+		result = appendMeta(result, "size", strconv.FormatInt(m.Size, 10))
+	}
+	result = appendMeta(result, "type", m.Type)
+	result = appendMeta(result, "license", m.License)
+	result = appendMeta(result, "description", m.Description)
+	return result
+}
+
+// appendMeta adds a metadata line to lines if the value isn't empty,
+// collapsing any whitespace so the value fits on a single line.
+func appendMeta(lines []string, key, value string) []string {
+	value = strings.Join(strings.Fields(value), " ")
+	if value == "" {
+		return lines
+	}
+	return append(lines, MetaPrefix+" "+key+" "+value)
+}
+
+// parseMeta splits a metadata line into its key and value.
+func parseMeta(line string) (key, value string, ok bool) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, MetaPrefix) {
+		return "", "", false
+	}
+	fields := strings.SplitN(strings.TrimSpace(strings.TrimPrefix(line, MetaPrefix)), " ", 2)
+	key = strings.ToLower(fields[0])
+	if len(fields) == 2 {
+		value = strings.TrimSpace(fields[1])
+	}
+	return key, value, key != ""
+}
@@ -1,21 +1,12 @@
 package manifest
 
-import (
-	"errors"
-	"net/url"
-
-	"github.com/arken/ark/manifest/upstream"
-)
-
+// HaveWriteAccess reports whether the user can push changes straight to
+// the manifest. Manifests on unknown hosts can only be pushed to, so they
+// are reported as writable along with the error "unknown upstream".
 func (m *Manifest) HaveWriteAccess() (bool, error) {
-	url, err := url.Parse(m.url)
+	upstream, url, err := findUpstream(m.url)
 	if err != nil {
-		return false, err
-	}
-
-	upstream, ok := upstream.AvailableUpstreams[url.Host]
-	if !ok {
-		return true, errors.New("unknown upstream")
+		return url != nil, err
 	}
 	return upstream.HaveWriteAccess(m.gitOpts.Token, *url)
 }
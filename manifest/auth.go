@@ -8,14 +8,46 @@ import (
 )
 
 func Auth(path string) (result upstream.Guard, err error) {
-	url, err := url.Parse(path)
+	upstream, _, err := findUpstream(path)
 	if err != nil {
 		return nil, err
 	}
+	return upstream.Auth(path)
+}
+
+// CheckToken checks that a token is valid for the upstream of the
+// manifest at path, returning the user it belongs to and its scopes.
+func CheckToken(path, token string) (upstream.TokenInfo, error) {
+	up, _, err := findUpstream(path)
+	if err != nil {
+		return upstream.TokenInfo{}, err
+	}
+	return up.CheckToken(token)
+}
+
+// NeedsToken reports whether changes to the manifest at path are
+// submitted with an access token, rather than only the credentials
+// of the underlying git transport.
+func NeedsToken(path string) bool {
+	up, _, err := findUpstream(path)
+	if err != nil {
+		return true
+	}
+	_, ok := up.(*upstream.Git)
+	return !ok
+}
+
+// findUpstream parses the location of a repository and finds
+// the upstream registered for its host.
+func findUpstream(location string) (upstream.Upstream, *url.URL, error) {
+	url, err := ParseLocation(location)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	upstream, ok := upstream.AvailableUpstreams[url.Host]
 	if !ok {
-		return nil, errors.New("unknown upstream")
+		return nil, url, errors.New("unknown upstream")
 	}
-	return upstream.Auth(path)
+	return upstream, url, nil
 }
@@ -2,6 +2,8 @@ package manifest
 
 import (
 	"errors"
+	"path/filepath"
+	"regexp"
 	"strings"
 
 	"github.com/go-git/go-git/v5"
@@ -60,7 +62,12 @@ func (m *Manifest) PullBranch(branchName string) (err error) {
 		return err
 	}
 
-	refs, err := rem.List(&git.ListOptions{})
+	auth, err := m.auth(m.forkUrl)
+	if err != nil {
+		return err
+	}
+
+	refs, err := rem.List(&git.ListOptions{Auth: auth})
 	if err != nil {
 		return err
 	}
@@ -76,11 +83,75 @@ func (m *Manifest) PullBranch(branchName string) (err error) {
 		return errors.New("branch not found")
 	}
 
-	err = m.r.CreateBranch(&config.Branch{Name: branchName, Remote: "origin", Merge: localBranchReferenceName})
+	// Fetch the branch and point the local branch at it.
+	refSpec := config.RefSpec("+" + localBranchReferenceName + ":" + remoteReferenceName)
+	err = rem.Fetch(&git.FetchOptions{
+		RemoteName: "fork",
+		RefSpecs:   []config.RefSpec{refSpec},
+		Auth:       auth,
+	})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return err
+	}
+	remoteRef, err := m.r.Reference(remoteReferenceName, true)
 	if err != nil {
 		return err
 	}
-	newReference := plumbing.NewSymbolicReference(localBranchReferenceName, remoteReferenceName)
+	newReference := plumbing.NewHashReference(localBranchReferenceName, remoteRef.Hash())
 	err = m.r.Storer.SetReference(newReference)
 	return err
 }
+
+// branchUnsafe matches the characters replaced within branch names.
+var branchUnsafe = regexp.MustCompile(`[^A-Za-z0-9._-]+|\.\.+`)
+
+// SubmissionBranch names the branch a change to the keyset at path,
+// relative to the root of the manifest, is submitted on, such as
+// "submit/alice/docs/books.ks". Branches are named after the user, as
+// every submission to a plain git server shares the same repository.
+func (m *Manifest) SubmissionBranch(action, path string) string {
+	user := m.gitOpts.Username
+	if user == "" {
+		user = m.gitOpts.Email
+	}
+	return branchName(action, user, path)
+}
+
+// ResetToOrigin discards the commits made to the current branch since it
+// was last pulled from the origin, along with any changes to the worktree.
+func (m *Manifest) ResetToOrigin() error {
+	h, err := m.r.Head()
+	if err != nil {
+		return err
+	}
+	ref, err := m.r.Reference(plumbing.NewRemoteReferenceName("origin", h.Name().Short()), true)
+	if err != nil {
+		return err
+	}
+	w, err := m.r.Worktree()
+	if err != nil {
+		return err
+	}
+	return w.Reset(&git.ResetOptions{Commit: ref.Hash(), Mode: git.HardReset})
+}
+
+// branchName joins the components of paths onto a prefix to name a
+// branch, replacing anything git doesn't allow within branch names.
+func branchName(prefix string, paths ...string) string {
+	result := []string{prefix}
+	for _, path := range paths {
+		for _, component := range strings.Split(filepath.ToSlash(path), "/") {
+			component = branchUnsafe.ReplaceAllStringFunc(component, func(match string) string {
+				if strings.HasPrefix(match, ".") {
+					return "."
+				}
+				return "-"
+			})
+			component = strings.TrimSuffix(strings.Trim(component, ".-"), ".lock")
+			if component != "" {
+				result = append(result, component)
+			}
+		}
+	}
+	return strings.Join(result, "/")
+}
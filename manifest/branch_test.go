@@ -0,0 +1,65 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBranchName(t *testing.T) {
+	tests := []struct {
+		prefix string
+		paths  []string
+		want   string
+	}{
+		{"retract", []string{"docs/a.ks"}, "retract/docs/a.ks"},
+		{"retract", []string{filepath.Join("docs", "guides", "a.ks")}, "retract/docs/guides/a.ks"},
+		{"submit", []string{"Bob Smith", ".", "a.ks"}, "submit/Bob-Smith/a.ks"},
+		{"submit", []string{"alice", "My Docs/x~y^z:.ks"}, "submit/alice/My-Docs/x-y-z-.ks"},
+		{"replace", []string{".hidden/a..b.ks.lock"}, "replace/hidden/a.b.ks"},
+	}
+	for _, test := range tests {
+		if got := branchName(test.prefix, test.paths...); got != test.want {
+			t.Errorf("branchName(%q, %q) = %q, want %q", test.prefix, test.paths, got, test.want)
+		}
+	}
+}
+
+func TestResetToOrigin(t *testing.T) {
+	remote := newTestRemote(t, map[string]string{"config.toml": "name = \"test\"\n"})
+	m := openTestManifest(t, remote)
+
+	// A commit that couldn't be pushed is discarded.
+	path := filepath.Join(m.path, "docs", "a.ks")
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(path, []byte("QmT78zSuBmuS4z925WZfrqQ1qHaJ56DQaTfyMUF7F8ff5o  a.txt\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = m.Commit(path, "Add docs/a.ks")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = m.ResetToOrigin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("docs/a.ks is still in the worktree: %v", err)
+	}
+	head, err := m.r.Head()
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit, err := m.r.CommitObject(head.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if commit.Message != "Initial commit" {
+		t.Errorf("HEAD is %q, want the initial commit", commit.Message)
+	}
+}
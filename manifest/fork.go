@@ -1,29 +1,25 @@
 package manifest
 
 import (
-	"errors"
-	"net/url"
-
 	"github.com/arken/ark/manifest/upstream"
 	"github.com/go-git/go-git/v5/config"
 )
 
 func (m *Manifest) Fork() error {
-	url, err := url.Parse(m.url)
+	// Check for matching upstream.
+	up, url, err := findUpstream(m.url)
 	if err != nil {
 		return err
 	}
 
-	// Check for matching upstream.
-	upstream, ok := upstream.AvailableUpstreams[url.Host]
-	if !ok {
-		return errors.New("unknown upstream")
-	}
-
 	// If an upstream if found use it to fork the repository.
-	m.forkUrl, err = upstream.Fork(m.gitOpts.Token, *url)
-	if err != nil {
-		return err
+	if g, ok := up.(*upstream.Git); ok {
+		m.forkUrl = m.submissionRemote(g)
+	} else {
+		m.forkUrl, err = up.Fork(m.gitOpts.Token, *url)
+		if err != nil {
+			return err
+		}
 	}
 
 	_, err = m.r.CreateRemote(&config.RemoteConfig{
@@ -1,13 +1,38 @@
 package manifest
 
-import "strings"
+import (
+	"strings"
 
-func (m *Manifest) Generate(input map[string]string) (string, error) {
-	output := make([]string, 0, len(input))
+	"github.com/arken/ark/keyset"
+)
 
-	for k, v := range input {
-		output = append(output, k+"  "+v)
+// Generated is the contents of a keyset file and of its metadata file,
+// which is empty if the keyset has no header or metadata.
+type Generated struct {
+	Keyset string
+	Meta   string
+}
+
+// Generate builds the contents of a keyset file and its metadata file
+// from its header and entries, sorted so the output is the same every
+// time. Every path is kept, including identical files listed under
+// different paths, which are reported as duplicates.
+func (m *Manifest) Generate(k *keyset.Keyset) (Generated, []keyset.Duplicate, error) {
+	entries, duplicates := keyset.Dedupe(k.Entries)
+	deduped := &keyset.Keyset{Header: k.Header, Entries: entries}
+
+	result := Generated{}
+	output := &strings.Builder{}
+	err := keyset.Write(output, deduped)
+	if err != nil {
+		return result, duplicates, err
 	}
+	result.Keyset = output.String()
 
-	return strings.Join(output, "\n"), nil
+	if deduped.HasMeta() {
+		output.Reset()
+		err = keyset.WriteMeta(output, deduped)
+		result.Meta = output.String()
+	}
+	return result, duplicates, err
 }
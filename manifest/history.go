@@ -0,0 +1,26 @@
+package manifest
+
+import (
+	"errors"
+	"path/filepath"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// LastModified returns the time of the most recent commit that
+// changed a keyset, relative to the root of the manifest.
+func (m *Manifest) LastModified(keyset string) (time.Time, error) {
+	name := filepath.ToSlash(keyset)
+	iter, err := m.r.Log(&git.LogOptions{FileName: &name})
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer iter.Close()
+
+	commit, err := iter.Next()
+	if err != nil {
+		return time.Time{}, errors.New("no history found for " + keyset)
+	}
+	return commit.Committer.When, nil
+}
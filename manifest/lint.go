@@ -0,0 +1,324 @@
+package manifest
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/arken/ark/keyset"
+	"github.com/libp2p/go-libp2p-core/peer"
+	ma "github.com/multiformats/go-multiaddr"
+)
+
+// Rules reported by Lint.
+const (
+	RuleConfig       string = "config"
+	RuleUnknownKey   string = "config-unknown-key"
+	RuleMalformed    string = "malformed-line"
+	RuleInvalidCID   string = "invalid-cid"
+	RuleDuplicate    string = "duplicate-entry"
+	RuleDuplicateCID string = "duplicate-cid"
+	RuleBacktracking string = "path-backtracking"
+	RuleNaming       string = "category-naming"
+	RuleMeta         string = "metadata"
+	RuleOrphanMeta   string = "metadata-unlisted"
+)
+
+// Severities of the problems reported by Lint.
+const (
+	SeverityError   string = "error"
+	SeverityWarning string = "warning"
+)
+
+// warningRules are the rules reported as warnings rather than errors.
+// Identical files may be listed under several paths, as submit keeps
+// them, and metadata of unlisted entries is never read.
+var warningRules = map[string]bool{
+	RuleDuplicateCID: true,
+	RuleOrphanMeta:   true,
+}
+
+// Problem is a single issue found while linting a manifest repository.
+type Problem struct {
+	File     string `json:"file"`
+	Line     int    `json:"line,omitempty"`
+	Rule     string `json:"rule"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+func (p Problem) String() string {
+	location := p.File
+	if p.Line > 0 {
+		location = fmt.Sprintf("%s:%d", p.File, p.Line)
+	}
+	return fmt.Sprintf("%s: %s: %s [%s]", location, p.Severity, p.Message, p.Rule)
+}
+
+// Lint checks the configuration, keysets, and keyset metadata files of
+// the manifest repository at path and returns every problem it finds.
+// The returned error is only set if the repository itself couldn't be
+// read.
+func Lint(path string) ([]Problem, error) {
+	result := lintConfig(path)
+
+	// Where each CID was first listed, and the entries of each keyset
+	// for checking its metadata file, which is walked after it.
+	cids := make(map[string]string)
+	keysets := make(map[string]map[keyset.Entry]bool)
+
+	err := filepath.Walk(path, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && strings.HasPrefix(info.Name(), ".") && file != path {
+			return filepath.SkipDir
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(path, file)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		switch {
+		case strings.HasSuffix(rel, ".ks"):
+			result = append(result, lintNames(rel)...)
+			entries, problems, err := lintKeyset(file, rel, cids)
+			keysets[rel] = entries
+			result = append(result, problems...)
+			return err
+		case strings.HasSuffix(rel, ".ks"+keyset.MetaSuffix):
+			problems, err := lintMeta(file, rel, keysets[strings.TrimSuffix(rel, keyset.MetaSuffix)])
+			result = append(result, problems...)
+			return err
+		}
+		return nil
+	})
+
+	for i, problem := range result {
+		result[i].Severity = SeverityError
+		if warningRules[problem.Rule] {
+			result[i].Severity = SeverityWarning
+		}
+	}
+	return result, err
+}
+
+// lintConfig checks the manifest's config.toml against the Manifest schema.
+func lintConfig(path string) (result []Problem) {
+	problem := func(format string, args ...interface{}) {
+		result = append(result, Problem{
+			File:    "config.toml",
+			Rule:    RuleConfig,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	m := Manifest{}
+	meta, err := toml.DecodeFile(filepath.Join(path, "config.toml"), &m)
+	if err != nil {
+		problem("%v", err)
+		return result
+	}
+
+	for _, key := range meta.Undecoded() {
+		result = append(result, Problem{
+			File:    "config.toml",
+			Rule:    RuleUnknownKey,
+			Message: fmt.Sprintf("unknown key %q", key.String()),
+		})
+	}
+
+	if strings.TrimSpace(m.Name) == "" {
+		problem("name is required")
+	}
+	if m.Replications < 0 {
+		problem("replications cannot be negative")
+	}
+	if m.ClusterKey != "" {
+		key, err := hex.DecodeString(m.ClusterKey)
+		if err != nil || len(key) != 32 {
+			problem("cluster_key must be 64 hexadecimal characters")
+		}
+	}
+	for _, addr := range m.BootstrapPeers {
+		maddr, err := ma.NewMultiaddr(addr)
+		if err == nil {
+			_, err = peer.AddrInfoFromP2pAddr(maddr)
+		}
+		if err != nil {
+			problem("invalid bootstrap peer %q: %v", addr, err)
+		}
+	}
+	return result
+}
+
+// lintNames checks that the category and name of a keyset follow
+// the naming rules for manifests.
+func lintNames(rel string) (result []Problem) {
+	components := strings.Split(rel, "/")
+	components[len(components)-1] = strings.TrimSuffix(components[len(components)-1], ".ks")
+	for i, name := range components {
+		kind := "category"
+		if i == len(components)-1 {
+			kind = "keyset"
+		}
+		err := keyset.CheckName(kind, name)
+		if err != nil {
+			result = append(result, Problem{
+				File:    rel,
+				Rule:    RuleNaming,
+				Message: err.Error(),
+			})
+		}
+	}
+	return result
+}
+
+// lintKeyset checks every line of a keyset file and returns its valid
+// entries. Listing the same CID and path twice is an error, while a CID
+// already listed elsewhere in the manifest, recorded in cids, is a
+// warning.
+func lintKeyset(file, rel string, cids map[string]string) (entries map[keyset.Entry]bool, result []Problem, err error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	// Remember where each entry was first seen to report duplicates.
+	seen := make(map[keyset.Entry]int)
+
+	reader := keyset.NewReader(f)
+	for {
+		entry, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		var parseErr *keyset.ParseError
+		if errors.As(err, &parseErr) {
+			result = append(result, Problem{
+				File:    rel,
+				Line:    parseErr.Line,
+				Rule:    RuleMalformed,
+				Message: parseErr.Err.Error(),
+			})
+			continue
+		}
+		if err != nil {
+			return seenEntries(seen), result, err
+		}
+
+		err = entry.Validate()
+		if err != nil {
+			rule := RuleMalformed
+			switch {
+			case errors.Is(err, keyset.ErrInvalidCID):
+				rule = RuleInvalidCID
+			case errors.Is(err, keyset.ErrBacktracking):
+				rule = RuleBacktracking
+			}
+			result = append(result, Problem{
+				File:    rel,
+				Line:    reader.Line(),
+				Rule:    rule,
+				Message: err.Error(),
+			})
+			continue
+		}
+
+		key := keyset.Entry{CID: entry.CID, Path: entry.Path}
+		if first, ok := seen[key]; ok {
+			result = append(result, Problem{
+				File:    rel,
+				Line:    reader.Line(),
+				Rule:    RuleDuplicate,
+				Message: fmt.Sprintf("%s is already listed with the same CID on line %d", entry.Path, first),
+			})
+			continue
+		}
+		seen[key] = reader.Line()
+
+		location := fmt.Sprintf("%s:%d (%s)", rel, reader.Line(), entry.Path)
+		if first, ok := cids[entry.CID]; ok {
+			result = append(result, Problem{
+				File:    rel,
+				Line:    reader.Line(),
+				Rule:    RuleDuplicateCID,
+				Message: fmt.Sprintf("%s has the same CID as %s", entry.Path, first),
+			})
+			continue
+		}
+		cids[entry.CID] = location
+	}
+	return seenEntries(seen), result, nil
+}
+
+// seenEntries returns the set of entries within seen.
+func seenEntries(seen map[keyset.Entry]int) map[keyset.Entry]bool {
+	result := make(map[keyset.Entry]bool, len(seen))
+	for entry := range seen {
+		result[entry] = true
+	}
+	return result
+}
+
+// lintMeta checks the metadata file of a keyset holding entries, which
+// is nil if there's no such keyset.
+func lintMeta(file, rel string, entries map[keyset.Entry]bool) (result []Problem, err error) {
+	if entries == nil {
+		return []Problem{{
+			File:    rel,
+			Rule:    RuleMeta,
+			Message: fmt.Sprintf("there is no keyset %s for this metadata", strings.TrimSuffix(rel, keyset.MetaSuffix)),
+		}}, nil
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	reader := keyset.NewReader(f)
+	for {
+		entry, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+
+		var parseErr *keyset.ParseError
+		if errors.As(err, &parseErr) {
+			result = append(result, Problem{
+				File:    rel,
+				Line:    parseErr.Line,
+				Rule:    RuleMalformed,
+				Message: parseErr.Err.Error(),
+			})
+			continue
+		}
+		if err != nil {
+			return result, err
+		}
+
+		if !entries[keyset.Entry{CID: entry.CID, Path: entry.Path}] {
+			result = append(result, Problem{
+				File:    rel,
+				Line:    reader.Line(),
+				Rule:    RuleOrphanMeta,
+				Message: fmt.Sprintf("%s (%s) isn't listed in the keyset", entry.Path, entry.CID),
+			})
+		}
+	}
+	return result, nil
+}
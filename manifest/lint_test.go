@@ -0,0 +1,74 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	const (
+		cidA = "QmT78zSuBmuS4z925WZfrqQ1qHaJ56DQaTfyMUF7F8ff5o"
+		cidB = "QmXgm5QVTy8pRtKrTPmoWPGXNesehCpP4jjFMTpvGamc1p"
+	)
+	dir := t.TempDir()
+	files := map[string]string{
+		"config.toml": "name = \"test\"\n",
+		"docs/a.ks": cidA + "  a.txt\n" +
+			cidA + "  copy/a.txt\n" +
+			cidA + "  a.txt\n" +
+			cidB + "  ../b.txt\n" +
+			"not-a-cid  c.txt\n",
+		"docs/a.ks.meta": "#: title A\n\n" +
+			"#: size 6\n" + cidA + "  a.txt\n" +
+			"#: size -1\n" +
+			"#: size 3\n" + cidB + "  gone.txt\n",
+		"docs/Bad Name.ks":   cidB + "  b.txt\n",
+		"docs/other.ks.meta": "#: title Other\n",
+		"docs/z.ks":          cidA + "  z.txt\n",
+		".git/ignored.ks":    "not-a-cid\n",
+	}
+	for name, contents := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		err := os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(path, []byte(contents), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	problems, err := Lint(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	type found struct {
+		File     string
+		Line     int
+		Rule     string
+		Severity string
+	}
+	got := []found{}
+	for _, problem := range problems {
+		got = append(got, found{problem.File, problem.Line, problem.Rule, problem.Severity})
+	}
+	want := []found{
+		{"docs/Bad Name.ks", 0, RuleNaming, SeverityError},
+		// The same file listed under another path, in this keyset or
+		// another, is only a warning.
+		{"docs/a.ks", 2, RuleDuplicateCID, SeverityWarning},
+		{"docs/a.ks", 3, RuleDuplicate, SeverityError},
+		{"docs/a.ks", 4, RuleBacktracking, SeverityError},
+		{"docs/a.ks", 5, RuleInvalidCID, SeverityError},
+		{"docs/a.ks.meta", 5, RuleMalformed, SeverityError},
+		{"docs/a.ks.meta", 7, RuleOrphanMeta, SeverityWarning},
+		{"docs/other.ks.meta", 0, RuleMeta, SeverityError},
+		{"docs/z.ks", 1, RuleDuplicateCID, SeverityWarning},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Lint found:\n%+v\nwant:\n%+v", got, want)
+	}
+}
@@ -0,0 +1,96 @@
+package manifest
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/arken/ark/keyset"
+)
+
+// Node is a category or keyset within a manifest's category tree.
+type Node struct {
+	Name     string
+	IsKeyset bool
+	Entries  int
+}
+
+// List returns the categories and keysets directly within a category
+// of the manifest, sorted by name with categories first.
+func (m *Manifest) List(category string) ([]Node, error) {
+	category = filepath.Clean(category)
+	if strings.Contains(category, "..") {
+		return nil, errors.New("path backtracking (\"..\") is not allowed in the category")
+	}
+
+	infos, err := os.ReadDir(filepath.Join(m.path, category))
+	if os.IsNotExist(err) {
+		return nil, errors.New("category not found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := []Node{}
+	for _, info := range infos {
+		// Skip repository metadata such as .git
+		if strings.HasPrefix(info.Name(), ".") {
+			continue
+		}
+		if info.IsDir() {
+			result = append(result, Node{Name: info.Name()})
+			continue
+		}
+		if !strings.HasSuffix(info.Name(), ".ks") {
+			continue
+		}
+
+		entries, err := countEntries(filepath.Join(m.path, category, info.Name()))
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, Node{
+			Name:     info.Name(),
+			IsKeyset: true,
+			Entries:  entries,
+		})
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].IsKeyset != result[j].IsKeyset {
+			return !result[i].IsKeyset
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+// countEntries counts the number of entries within a keyset file,
+// skipping malformed lines like Walk does.
+func countEntries(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	count := 0
+	reader := keyset.NewReader(f)
+	for {
+		_, err := reader.Read()
+		if err == io.EOF {
+			return count, nil
+		}
+		var parseErr *keyset.ParseError
+		if errors.As(err, &parseErr) {
+			continue
+		}
+		if err != nil {
+			return count, err
+		}
+		count++
+	}
+}
@@ -20,11 +20,18 @@ type Manifest struct {
 	gitOpts        GitOptions
 }
 
+// GitOptions are the identity and credentials used for git operations on
+// a manifest. Username and Token authenticate HTTP remotes, and SSHKey is
+// the path of the private key used for SSH remotes instead of the SSH
+// agent. Passphrase is asked for the passphrase of an encrypted key, and
+// is nil when the user can't be asked.
 type GitOptions struct {
-	Name     string
-	Username string
-	Token    string
-	Email    string
+	Name       string
+	Username   string
+	Token      string
+	Email      string
+	SSHKey     string
+	Passphrase func(key string) (string, error)
 }
 
 // Init Clones/Pulls a Manifest Repository and Parses the Config
@@ -41,9 +48,7 @@ func Init(path, url string, opts GitOptions) (*Manifest, error) {
 	// Check if Git Repository Exists
 	result.r, err = git.PlainOpen(path)
 	if err != nil && err.Error() == "repository does not exist" {
-		result.r, err = git.PlainClone(path, false, &git.CloneOptions{
-			URL: url,
-		})
+		result.r, err = result.clone()
 	}
 	if err != nil {
 		return nil, err
@@ -60,6 +65,18 @@ func Init(path, url string, opts GitOptions) (*Manifest, error) {
 	return &result, err
 }
 
+// clone clones the manifest repository into its path.
+func (m *Manifest) clone() (*git.Repository, error) {
+	auth, err := m.auth(m.url)
+	if err != nil {
+		return nil, err
+	}
+	return git.PlainClone(m.path, false, &git.CloneOptions{
+		URL:  m.url,
+		Auth: auth,
+	})
+}
+
 // Decode Manifest Configuration from Manifest Repository
 func (m *Manifest) Decode() error {
 	_, err := toml.DecodeFile(filepath.Join(m.path, "config.toml"), m)
@@ -13,7 +13,11 @@ func (m *Manifest) Pull() error {
 	}
 
 	// Check for updates to the Manifest Repository
-	err = w.Pull(&git.PullOptions{RemoteName: "origin"})
+	auth, err := m.auth(m.url)
+	if err != nil {
+		return err
+	}
+	err = w.Pull(&git.PullOptions{RemoteName: "origin", Auth: auth})
 	if err != nil && err != git.NoErrAlreadyUpToDate {
 		return err
 	}
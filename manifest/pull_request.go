@@ -1,38 +1,38 @@
 package manifest
 
-import (
-	"errors"
-	"net/url"
+import "github.com/arken/ark/manifest/upstream"
 
-	"github.com/arken/ark/manifest/upstream"
-)
-
-func (m *Manifest) OpenPR(mainBranch, prTitle, prBody string) error {
-	url, err := url.Parse(m.url)
+// OpenPR opens a pull request from the current branch of the fork to
+// the main branch of the manifest, returning the pull request opened.
+func (m *Manifest) OpenPR(mainBranch, prTitle, prBody string) (upstream.PullRequest, error) {
+	// Check for matching upstream.
+	up, url, err := findUpstream(m.url)
 	if err != nil {
-		return err
+		return upstream.PullRequest{}, err
 	}
 
-	// Check for matching upstream.
-	up, ok := upstream.AvailableUpstreams[url.Host]
-	if !ok {
-		return errors.New("unknown upstream")
+	fork, err := ParseLocation(m.forkUrl)
+	if err != nil {
+		return upstream.PullRequest{}, err
 	}
 
-	fork, err := url.Parse(m.forkUrl)
+	prBranch, err := m.GetBranchName()
 	if err != nil {
-		return err
+		return upstream.PullRequest{}, err
 	}
 
-	prBranch, err := m.GetBranchName()
+	auth, err := m.auth(m.forkUrl)
 	if err != nil {
-		return err
+		return upstream.PullRequest{}, err
 	}
 
 	opts := upstream.PrOpts{
 		Origin:     *url,
 		Fork:       *fork,
 		Token:      m.gitOpts.Token,
+		Name:       m.gitOpts.Name,
+		Email:      m.gitOpts.Email,
+		Auth:       auth,
 		MainBranch: mainBranch,
 		PrBranch:   prBranch,
 		PrTitle:    prTitle,
@@ -40,20 +40,38 @@ func (m *Manifest) OpenPR(mainBranch, prTitle, prBody string) error {
 	}
 
 	// Attempt to open a PR using the found upstream.
+	if g, ok := up.(*upstream.Git); ok {
+		return g.Submit(m.forkUrl, opts)
+	}
 	return up.OpenPR(opts)
 }
 
-func (m *Manifest) SearchPrByBranch(branchName string) error {
-	url, err := url.Parse(m.url)
+// SearchPrByBranch returns the open pull request from a branch, if any.
+func (m *Manifest) SearchPrByBranch(branchName string) (upstream.PullRequest, error) {
+	// Check for matching upstream.
+	up, url, err := findUpstream(m.url)
 	if err != nil {
-		return err
+		return upstream.PullRequest{}, err
 	}
 
-	// Check for matching upstream.
-	upstream, ok := upstream.AvailableUpstreams[url.Host]
-	if !ok {
-		return errors.New("unknown upstream")
+	return up.SearchPrByBranch(*url, m.gitOpts.Token, branchName)
+}
+
+// PrStatus checks the state of a pull request to the manifest at path.
+func PrStatus(path string, opts GitOptions, pr upstream.PullRequest) (upstream.PrStatus, error) {
+	up, url, err := findUpstream(path)
+	if err != nil {
+		return upstream.PrStatus{}, err
 	}
 
-	return upstream.SearchPrByBranch(*url, m.gitOpts.Token, branchName)
+	// Plain git servers need the transport's credentials rather than a token.
+	if _, ok := up.(*upstream.Git); ok {
+		m := &Manifest{url: path, gitOpts: opts}
+		g, remote, auth, err := m.gitUpstream()
+		if err != nil {
+			return upstream.PrStatus{}, err
+		}
+		return g.Status(remote, auth, pr)
+	}
+	return up.PrStatus(*url, opts.Token, pr)
 }
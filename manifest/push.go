@@ -3,25 +3,37 @@ package manifest
 import (
 	"github.com/go-git/go-git/v5"
 	"github.com/go-git/go-git/v5/config"
-	"github.com/go-git/go-git/v5/plumbing/transport/http"
 )
 
-// Push performs a "git push" on the repository.
+// Push performs a "git push" of the current branch to the fork
+// if one was set up, or otherwise to the origin.
 func (m *Manifest) Push() (err error) {
 	h, err := m.r.Head()
 	if err != nil {
 		return err
 	}
+	remote, remoteURL := "origin", m.url
+	if m.forkUrl != "" {
+		remote, remoteURL = "fork", m.forkUrl
+	}
 	// Generate <src>:<dest> reference string
 	refStr := h.Name().String() + ":" + h.Name().String()
-	// Push Branch to Origin
+	return m.push(remote, remoteURL, config.RefSpec(refStr))
+}
+
+// push pushes refs to a remote of the repository.
+func (m *Manifest) push(remote, remoteURL string, refSpecs ...config.RefSpec) error {
+	auth, err := m.auth(remoteURL)
+	if err != nil {
+		return err
+	}
 	err = m.r.Push(&git.PushOptions{
-		RemoteName: "fork",
-		RefSpecs:   []config.RefSpec{config.RefSpec(refStr)},
-		Auth: &http.BasicAuth{
-			Username: m.gitOpts.Username,
-			Password: m.gitOpts.Token,
-		},
+		RemoteName: remote,
+		RefSpecs:   refSpecs,
+		Auth:       auth,
 	})
+	if err == git.NoErrAlreadyUpToDate {
+		return nil
+	}
 	return err
 }
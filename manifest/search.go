@@ -1,44 +1,143 @@
 package manifest
 
 import (
-	"bufio"
+	"errors"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
+
+	"github.com/arken/ark/keyset"
 )
 
-func (m *Manifest) Search(path string) (map[string][]string, error) {
-	// Create hashes map to hold results
-	hashes := make(map[string][]string)
+// Search finds the entries whose paths match the base of the input path
+// within the keysets named by its directory, grouped by their paths.
+func (m *Manifest) Search(path string) (map[string][]Entry, error) {
+	// Create results map to hold matches
+	results := make(map[string][]Entry)
 
 	// Define the file's category to improve search times.
 	category := filepath.Dir(path)
 	base := filepath.Base(path)
 
-	err := filepath.Walk(m.path, func(path string, info os.FileInfo, err error) error {
-		if strings.HasSuffix(path, category+".ks") {
-			file, err := os.Open(path)
+	err := m.Walk(func(entry Entry) error {
+		if category != "." && !strings.HasSuffix(filepath.Join(m.path, entry.Keyset), category+".ks") {
+			return nil
+		}
+		matched, _ := filepath.Match(base, entry.Path)
+		if !matched {
+			matched, _ = filepath.Match(base, filepath.Base(entry.Path))
+		}
+		if matched {
+			results[entry.Path] = append(results[entry.Path], entry)
+		}
+		return nil
+	})
+	return results, err
+}
+
+// Entry is a single file listed within one of the manifest's keysets.
+type Entry struct {
+	keyset.Entry
+	Keyset string
+}
+
+// Category returns the category path of the keyset holding the entry.
+func (e Entry) Category() string {
+	return filepath.Dir(e.Keyset)
+}
+
+// Walk calls fn for every entry within every keyset of the manifest.
+// Keyset paths are relative to the root of the manifest. Malformed
+// lines are skipped, so one bad keyset doesn't hide the rest of the
+// manifest, and are left for "ark lint" to report.
+func (m *Manifest) Walk(fn func(entry Entry) error) error {
+	return filepath.Walk(m.path, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() && info.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if info.IsDir() || !strings.HasSuffix(path, ".ks") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(m.path, path)
+		if err != nil {
+			return err
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		// Read through the entries in the file.
+		reader := keyset.NewReader(file)
+		for {
+			entry, err := reader.Read()
+			if err == io.EOF {
+				return nil
+			}
+			var parseErr *keyset.ParseError
+			if errors.As(err, &parseErr) {
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("%s: %w", rel, err)
+			}
+			err = fn(Entry{Entry: entry, Keyset: rel})
 			if err != nil {
 				return err
 			}
+		}
+	})
+}
 
-			scanner := bufio.NewScanner(file)
+// Find returns every entry within the manifest that match reports true for.
+func (m *Manifest) Find(match func(entry Entry) bool) (result []Entry, err error) {
+	err = m.Walk(func(entry Entry) error {
+		if match(entry) {
+			result = append(result, entry)
+		}
+		return nil
+	})
+	return result, err
+}
 
-			// Scan through the lines in the file.
-			for scanner.Scan() {
-				// Split data on white space.
-				data := strings.Fields(scanner.Text())
+// Resolve returns the entries of a keyset, or of every keyset within
+// a category, from a path relative to the root of the manifest.
+func (m *Manifest) Resolve(target string) ([]Entry, error) {
+	target = filepath.Clean(target)
+	if strings.Contains(target, "..") {
+		return nil, errors.New("path backtracking (\"..\") is not allowed")
+	}
 
-				if matched, _ := filepath.Match(base, data[1]); matched {
-					if hashes[data[1]] == nil {
-						hashes[data[1]] = []string{}
-					}
-					hashes[data[1]] = append(hashes[data[1]], data[0])
-				}
-			}
+	info, err := os.Stat(filepath.Join(m.path, target))
+	if err != nil || (!info.IsDir() && !strings.HasSuffix(target, ".ks")) {
+		return nil, errors.New("no keyset or category found at " + target)
+	}
 
-		}
+	if !info.IsDir() {
+		return m.Find(func(entry Entry) bool {
+			return entry.Keyset == target
+		})
+	}
+	return m.Find(func(entry Entry) bool {
+		return target == "." || strings.HasPrefix(entry.Keyset, target+string(filepath.Separator))
+	})
+}
+
+// Locate maps every CID listed within the manifest to the keyset
+// files, relative to the root of the manifest, that contain it.
+func (m *Manifest) Locate() (map[string][]string, error) {
+	result := make(map[string][]string)
+	err := m.Walk(func(entry Entry) error {
+		result[entry.CID] = append(result[entry.CID], entry.Keyset)
 		return nil
 	})
-	return hashes, err
+	return result, err
 }
@@ -0,0 +1,48 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestWalkSkipsMalformedLines(t *testing.T) {
+	dir := t.TempDir()
+	err := os.MkdirAll(filepath.Join(dir, "docs"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(dir, "docs", "bad.ks"), []byte(
+		"QmT78zSuBmuS4z925WZfrqQ1qHaJ56DQaTfyMUF7F8ff5o\n"+
+			"QmT78zSuBmuS4z925WZfrqQ1qHaJ56DQaTfyMUF7F8ff5o  a.txt\n",
+	), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(filepath.Join(dir, "docs", "good.ks"), []byte(
+		"QmXgm5QVTy8pRtKrTPmoWPGXNesehCpP4jjFMTpvGamc1p  b.txt\n",
+	), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := &Manifest{path: dir}
+	paths := []string{}
+	err = m.Walk(func(entry Entry) error {
+		paths = append(paths, entry.Keyset+":"+entry.Path)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := filepath.Join("docs", "bad.ks") + ":a.txt," + filepath.Join("docs", "good.ks") + ":b.txt"
+	if got := strings.Join(paths, ","); got != want {
+		t.Errorf("Walk found %s, want %s", got, want)
+	}
+
+	count, err := countEntries(filepath.Join(dir, "docs", "bad.ks"))
+	if err != nil || count != 1 {
+		t.Errorf("countEntries = %d, %v, want 1", count, err)
+	}
+}
@@ -0,0 +1,304 @@
+package manifest
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/arken/ark/manifest/upstream"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
+
+var (
+	// ErrNoSubmissions is returned for manifests whose upstream reviews
+	// changes as pull requests rather than recording submissions.
+	ErrNoSubmissions = errors.New("only manifests on plain git servers record submissions, review its pull requests instead")
+	// ErrSubmissionNotFound is returned when merging a submission
+	// that isn't pending.
+	ErrSubmissionNotFound = errors.New("submission not found")
+)
+
+// ConflictError is returned when a submission changes files that
+// were also changed on the main branch since it was submitted.
+type ConflictError struct {
+	Paths []string
+}
+
+func (e *ConflictError) Error() string {
+	return "submission conflicts with changes to " + strings.Join(e.Paths, ", ")
+}
+
+// RecordsSubmissions reports whether the manifest is hosted on a plain
+// git server, which records submissions instead of pull requests.
+func (m *Manifest) RecordsSubmissions() bool {
+	up, _, err := findUpstream(m.url)
+	if err != nil {
+		return false
+	}
+	_, ok := up.(*upstream.Git)
+	return ok
+}
+
+// Submissions lists the pending submissions to the manifest.
+func (m *Manifest) Submissions() ([]upstream.Submission, error) {
+	g, remote, auth, err := m.gitUpstream()
+	if err != nil {
+		return nil, err
+	}
+	submissions, err := g.Submissions(remote, auth)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []upstream.Submission{}
+	for _, submission := range submissions {
+		if submission.State == upstream.PrOpen {
+			result = append(result, submission)
+		}
+	}
+	return result, nil
+}
+
+// Merge merges a pending submission into the current branch of the
+// manifest, pushes the result to the origin, and then removes the
+// submission's branch and records it as merged.
+func (m *Manifest) Merge(branch string) error {
+	submissions, err := m.Submissions()
+	if err != nil {
+		return err
+	}
+	var submission *upstream.Submission
+	for i := range submissions {
+		if submissions[i].Branch == branch {
+			submission = &submissions[i]
+		}
+	}
+	if submission == nil {
+		return ErrSubmissionNotFound
+	}
+
+	// Fetch the submission's branch from where it was pushed.
+	err = m.Fork()
+	if err != nil {
+		return err
+	}
+	auth, err := m.auth(m.forkUrl)
+	if err != nil {
+		return err
+	}
+	remoteRef := plumbing.NewRemoteReferenceName("fork", branch)
+	err = m.r.Fetch(&git.FetchOptions{
+		RemoteName: "fork",
+		RefSpecs: []config.RefSpec{
+			config.RefSpec("+" + plumbing.NewBranchReferenceName(branch) + ":" + remoteRef),
+		},
+		Auth: auth,
+	})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return err
+	}
+	ref, err := m.r.Reference(remoteRef, true)
+	if err != nil {
+		return err
+	}
+	tip, err := m.r.CommitObject(ref.Hash())
+	if err != nil {
+		return err
+	}
+
+	head, err := m.r.Head()
+	if err != nil {
+		return err
+	}
+	current, err := m.r.CommitObject(head.Hash())
+	if err != nil {
+		return err
+	}
+
+	// Compare what the submission and the current branch each changed.
+	bases, err := tip.MergeBase(current)
+	if err != nil {
+		return err
+	}
+	if len(bases) == 0 {
+		return fmt.Errorf("%s doesn't share any history with %s", branch, head.Name().Short())
+	}
+	submitted, err := treeChanges(bases[0], tip)
+	if err != nil {
+		return err
+	}
+	changed, err := treeChanges(bases[0], current)
+	if err != nil {
+		return err
+	}
+
+	conflicts := []string{}
+	for path, hash := range submitted {
+		if other, ok := changed[path]; ok {
+			if other != hash {
+				conflicts = append(conflicts, path)
+			}
+			delete(submitted, path)
+		}
+	}
+	if len(conflicts) > 0 {
+		return &ConflictError{Paths: conflicts}
+	}
+
+	// Apply the submission's changes to the worktree.
+	w, err := m.r.Worktree()
+	if err != nil {
+		return err
+	}
+	for path, hash := range submitted {
+		if hash.IsZero() {
+			_, err = w.Remove(path)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		blob, err := m.r.BlobObject(hash)
+		if err != nil {
+			return err
+		}
+		err = writeBlob(filepath.Join(m.path, filepath.FromSlash(path)), blob)
+		if err != nil {
+			return err
+		}
+		_, err = w.Add(path)
+		if err != nil {
+			return err
+		}
+	}
+
+	message := fmt.Sprintf("Merge submission %s", branch)
+	if submission.Title != "" {
+		message += "\n\n" + submission.Title
+	}
+	_, err = w.Commit(message, &git.CommitOptions{
+		Author: &object.Signature{
+			Name:  m.gitOpts.Name,
+			Email: m.gitOpts.Email,
+			When:  time.Now(),
+		},
+		Parents: []plumbing.Hash{current.Hash, tip.Hash},
+	})
+	if err != nil {
+		return err
+	}
+
+	err = m.push("origin", m.url, config.RefSpec(head.Name()+":"+head.Name()))
+	if err != nil {
+		return err
+	}
+
+	// Remove the merged branch, keeping its metadata so the
+	// submitter can see that it was merged.
+	err = m.push("fork", m.forkUrl, config.RefSpec(":"+plumbing.NewBranchReferenceName(branch)))
+	if err != nil {
+		return err
+	}
+	err = m.r.Storer.RemoveReference(remoteRef)
+	if err != nil {
+		return err
+	}
+
+	g, remote, auth, err := m.gitUpstream()
+	if err != nil {
+		return err
+	}
+	return g.SetState(remote, auth, *submission, upstream.PrMerged, object.Signature{
+		Name:  m.gitOpts.Name,
+		Email: m.gitOpts.Email,
+		When:  time.Now(),
+	})
+}
+
+// gitUpstream returns the manifest's upstream if it's a plain git server,
+// along with the repository submissions are pushed to and its auth.
+func (m *Manifest) gitUpstream() (*upstream.Git, string, transport.AuthMethod, error) {
+	up, _, err := findUpstream(m.url)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	g, ok := up.(*upstream.Git)
+	if !ok {
+		return nil, "", nil, ErrNoSubmissions
+	}
+
+	remote := m.submissionRemote(g)
+	auth, err := m.auth(remote)
+	return g, remote, auth, err
+}
+
+// submissionRemote returns the repository submissions to a manifest on a
+// plain git server are pushed to. The manifest's location is used as it
+// was written, so an scp-like path stays relative to the home directory
+// on the server.
+func (m *Manifest) submissionRemote(g *upstream.Git) string {
+	if g.Remote != "" {
+		return g.Remote
+	}
+	return m.url
+}
+
+// treeChanges maps each path changed between two commits to the hash
+// of its new contents, which is zero for deleted files.
+func treeChanges(from, to *object.Commit) (map[string]plumbing.Hash, error) {
+	fromTree, err := from.Tree()
+	if err != nil {
+		return nil, err
+	}
+	toTree, err := to.Tree()
+	if err != nil {
+		return nil, err
+	}
+	changes, err := object.DiffTree(fromTree, toTree)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]plumbing.Hash, len(changes))
+	for _, change := range changes {
+		if change.From.Name != "" {
+			result[change.From.Name] = plumbing.ZeroHash
+		}
+		if change.To.Name != "" {
+			result[change.To.Name] = change.To.TreeEntry.Hash
+		}
+	}
+	return result, nil
+}
+
+// writeBlob writes the contents of a blob to path.
+func writeBlob(path string, blob *object.Blob) error {
+	err := os.MkdirAll(filepath.Dir(path), os.ModePerm)
+	if err != nil {
+		return err
+	}
+	r, err := blob.Reader()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, r)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,200 @@
+package manifest
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/arken/ark/manifest/upstream"
+)
+
+// submitTestKeyset writes a keyset into a manifest on a new branch and
+// submits it the way "ark submit" does without write access.
+func submitTestKeyset(t *testing.T, m *Manifest, branch, name, contents string) upstream.PullRequest {
+	t.Helper()
+	err := m.Fork()
+	if err != nil {
+		t.Fatal(err)
+	}
+	mainBranch, err := m.GetBranchName()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = m.CreateBranch(branch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = m.SwitchBranch(branch)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(m.path, filepath.FromSlash(name))
+	err = os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.WriteFile(path, []byte(contents), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = m.Commit(path, "Submit "+name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = m.Push()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pr, err := m.OpenPR(mainBranch, "Submit "+name, "Adds "+name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = m.SwitchBranch(mainBranch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pr
+}
+
+func TestSubmissions(t *testing.T) {
+	const (
+		cidA = "QmT78zSuBmuS4z925WZfrqQ1qHaJ56DQaTfyMUF7F8ff5o"
+		cidB = "QmXgm5QVTy8pRtKrTPmoWPGXNesehCpP4jjFMTpvGamc1p"
+	)
+	remote := newTestRemote(t, map[string]string{
+		"config.toml": "name = \"test\"\n",
+		"docs/a.ks":   cidA + "  a.txt\n",
+	})
+	opts := GitOptions{Name: testSignature.Name, Email: testSignature.Email}
+
+	// Two contributors submit keysets from their own copies.
+	contributor := openTestManifest(t, remote)
+	pr := submitTestKeyset(t, contributor, "submit/b.ks", "docs/b.ks", cidB+"  b.txt\n")
+	if pr != (upstream.PullRequest{Branch: "submit/b.ks"}) {
+		t.Errorf("OpenPR = %+v", pr)
+	}
+	other := openTestManifest(t, remote)
+	submitTestKeyset(t, other, "submit/a.ks", "docs/a.ks", cidA+"  a.txt\n"+cidB+"  b.txt\n")
+
+	maintainer := openTestManifest(t, remote)
+	submissions, err := maintainer.Submissions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	branches := []string{}
+	for _, submission := range submissions {
+		branches = append(branches, submission.Branch)
+		if submission.State != upstream.PrOpen || submission.Base != "master" ||
+			submission.Author != "Test <test@example.com>" {
+			t.Errorf("unexpected submission %+v", submission)
+		}
+	}
+	if !reflect.DeepEqual(branches, []string{"submit/b.ks", "submit/a.ks"}) {
+		t.Errorf("Submissions = %v, want both submissions oldest first", branches)
+	}
+
+	// Merging a submission pushes it to the main branch and records
+	// it as merged.
+	err = maintainer.Merge("submit/b.ks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contents, _ := remoteFile(t, remote, "master", "docs/b.ks"); contents != cidB+"  b.txt\n" {
+		t.Errorf("docs/b.ks was merged as %q", contents)
+	}
+	status, err := PrStatus(remote, opts, pr)
+	if err != nil || status.State != upstream.PrMerged {
+		t.Errorf("PrStatus = %+v, %v, want merged", status, err)
+	}
+	err = maintainer.Merge("submit/b.ks")
+	if err != ErrSubmissionNotFound {
+		t.Errorf("merging twice returned %v", err)
+	}
+
+	// Changes to the same keyset on the main branch conflict.
+	path := filepath.Join(maintainer.path, "docs", "a.ks")
+	err = os.WriteFile(path, []byte(cidB+"  a.txt\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = maintainer.Commit(path, "Replace a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = maintainer.Push()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = maintainer.Merge("submit/a.ks")
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) || !reflect.DeepEqual(conflict.Paths, []string{"docs/a.ks"}) {
+		t.Errorf("Merge = %v, want a conflict on docs/a.ks", err)
+	}
+
+	// Closing the conflicting submission removes it from the pending ones.
+	g, gitRemote, auth, err := maintainer.gitUpstream()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature := testSignature
+	signature.When = time.Now()
+	err = g.SetState(gitRemote, auth, submissions[1], upstream.PrClosed, signature)
+	if err != nil {
+		t.Fatal(err)
+	}
+	submissions, err = maintainer.Submissions()
+	if err != nil || len(submissions) != 0 {
+		t.Errorf("Submissions = %+v, %v, want none pending", submissions, err)
+	}
+	status, err = PrStatus(remote, opts, upstream.PullRequest{Branch: "submit/a.ks"})
+	if err != nil || status.State != upstream.PrClosed {
+		t.Errorf("PrStatus = %+v, %v, want closed", status, err)
+	}
+}
+
+func TestSubmissionsFromTwoUsers(t *testing.T) {
+	const (
+		cidA = "QmT78zSuBmuS4z925WZfrqQ1qHaJ56DQaTfyMUF7F8ff5o"
+		cidB = "QmXgm5QVTy8pRtKrTPmoWPGXNesehCpP4jjFMTpvGamc1p"
+	)
+	remote := newTestRemote(t, map[string]string{"config.toml": "name = \"test\"\n"})
+
+	// Two users submit a keyset with the same name to the same repository.
+	alice := openTestManifestAs(t, remote, GitOptions{Name: "Alice", Email: "alice@example.com"})
+	bob := openTestManifestAs(t, remote, GitOptions{Name: "Bob", Username: "bob", Email: "bob@example.com"})
+	aliceBranch := alice.SubmissionBranch("submit", "docs/books.ks")
+	bobBranch := bob.SubmissionBranch("submit", "docs/books.ks")
+	if aliceBranch != "submit/alice-example.com/docs/books.ks" || bobBranch != "submit/bob/docs/books.ks" {
+		t.Errorf("SubmissionBranch = %q and %q", aliceBranch, bobBranch)
+	}
+	submitTestKeyset(t, alice, aliceBranch, "docs/books.ks", cidA+"  a.txt\n")
+	submitTestKeyset(t, bob, bobBranch, "docs/books.ks", cidB+"  b.txt\n")
+
+	// Neither submission replaces the other.
+	submissions, err := openTestManifest(t, remote).Submissions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	authors := map[string]string{}
+	for _, submission := range submissions {
+		authors[submission.Branch] = submission.Author
+	}
+	want := map[string]string{
+		aliceBranch: "Alice <alice@example.com>",
+		bobBranch:   "Bob <bob@example.com>",
+	}
+	if !reflect.DeepEqual(authors, want) {
+		t.Errorf("Submissions = %v, want %v", authors, want)
+	}
+	if contents, _ := remoteFile(t, remote, aliceBranch, "docs/books.ks"); contents != cidA+"  a.txt\n" {
+		t.Errorf("%s holds %q", aliceBranch, contents)
+	}
+	if contents, _ := remoteFile(t, remote, bobBranch, "docs/books.ks"); contents != cidB+"  b.txt\n" {
+		t.Errorf("%s holds %q", bobBranch, contents)
+	}
+}
@@ -0,0 +1,150 @@
+package manifest
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/arken/ark/manifest/upstream"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
+	cryptossh "golang.org/x/crypto/ssh"
+)
+
+// defaultSSHKeys are the keys within ~/.ssh tried for SSH remotes when
+// no key is configured and no SSH agent is running, in the order ssh
+// itself tries them.
+var defaultSSHKeys = []string{"id_rsa", "id_ecdsa", "id_ed25519"}
+
+// ParseLocation parses the location of a manifest repository into a URL.
+// Along with URLs, locations may be scp-like SSH addresses, such as
+// "git@example.com:org/manifest.git", or local paths, which are returned
+// without a scheme. The host includes any port other than the default
+// port of scp-like addresses.
+func ParseLocation(location string) (*url.URL, error) {
+	ep, err := transport.NewEndpoint(location)
+	if err != nil {
+		return nil, err
+	}
+	hasScheme := strings.Contains(location, "://")
+	if ep.Protocol == "file" && !hasScheme {
+		return &url.URL{Path: ep.Path}, nil
+	}
+
+	result := &url.URL{Scheme: ep.Protocol, Host: ep.Host, Path: ep.Path}
+	if ep.Port != 0 && (hasScheme || ep.Port != 22) {
+		result.Host = net.JoinHostPort(ep.Host, strconv.Itoa(ep.Port))
+	}
+	if result.Host != "" && !strings.HasPrefix(result.Path, "/") {
+		result.Path = "/" + result.Path
+	}
+	switch {
+	case ep.Password != "":
+		result.User = url.UserPassword(ep.User, ep.Password)
+	case ep.User != "":
+		result.User = url.User(ep.User)
+	}
+	return result, nil
+}
+
+// auth returns the credentials used to connect to a remote, chosen by
+// the scheme of its URL. SSH remotes use the configured key, or the SSH
+// agent if there isn't one, falling back to the default keys within
+// ~/.ssh, and HTTP remotes use a password within the URL or the
+// configured token. Other remotes, such as local paths, don't need any.
+func (m *Manifest) auth(remoteURL string) (transport.AuthMethod, error) {
+	ep, err := transport.NewEndpoint(remoteURL)
+	if err != nil {
+		return nil, err
+	}
+
+	switch ep.Protocol {
+	case "ssh":
+		user := ep.User
+		if user == "" {
+			user = "git"
+		}
+		if m.gitOpts.SSHKey != "" {
+			key, err := expandHome(m.gitOpts.SSHKey)
+			if err != nil {
+				return nil, err
+			}
+			return m.publicKeys(user, key)
+		}
+		auth, agentErr := ssh.NewSSHAgentAuth(user)
+		if agentErr == nil {
+			return auth, nil
+		}
+		for _, name := range defaultSSHKeys {
+			key, err := expandHome(filepath.Join("~", ".ssh", name))
+			if err != nil {
+				return nil, err
+			}
+			if _, err := os.Stat(key); err == nil {
+				return m.publicKeys(user, key)
+			}
+		}
+		return nil, agentErr
+	case "http", "https":
+		if ep.Password != "" {
+			return &http.BasicAuth{Username: ep.User, Password: ep.Password}, nil
+		}
+		if m.gitOpts.Token == "" {
+			return nil, nil
+		}
+		username := m.gitOpts.Username
+		if ep.User != "" {
+			username = ep.User
+		}
+		// GitLab only accepts OAuth tokens with the username "oauth2",
+		// which works for personal access tokens as well.
+		if up, _, err := findUpstream(remoteURL); err == nil {
+			if _, ok := up.(*upstream.GitLab); ok {
+				username = "oauth2"
+			}
+		}
+		return &http.BasicAuth{Username: username, Password: m.gitOpts.Token}, nil
+	}
+	return nil, nil
+}
+
+// publicKeys loads the private key at path, asking for its passphrase
+// if it's encrypted. Passphrases are never stored.
+func (m *Manifest) publicKeys(user, path string) (transport.AuthMethod, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	passphrase := ""
+	_, err = cryptossh.ParsePrivateKey(pem)
+	var missing *cryptossh.PassphraseMissingError
+	if errors.As(err, &missing) {
+		if m.gitOpts.Passphrase == nil {
+			return nil, fmt.Errorf("%s is encrypted, add it to your SSH agent with \"ssh-add\"", path)
+		}
+		passphrase, err = m.gitOpts.Passphrase(path)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ssh.NewPublicKeys(user, pem, passphrase)
+}
+
+// expandHome replaces a leading "~" in path with the user's home directory.
+func expandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~")), nil
+}
@@ -0,0 +1,291 @@
+package upstream
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// SubmissionRefPrefix is the prefix of the refs a Git upstream records
+// pending submissions under. Each ref points to a commit holding the
+// submission's metadata, and is named after the submission's branch.
+const SubmissionRefPrefix = "refs/ark/submissions/"
+
+// submissionFile is the name of the metadata file within a submission ref.
+const submissionFile = "submission.json"
+
+// Git is a wrapper struct for manifests hosted on a plain git server or
+// local path. Without a service to open pull requests on, submissions are
+// pushed as branches to Remote, along with a ref recording their metadata,
+// for maintainers to list and merge with Ark. An empty Remote pushes
+// submissions to the manifest repository itself.
+type Git struct {
+	Remote string
+}
+
+// Submission describes a pending submission to a Git upstream.
+type Submission struct {
+	Branch  string    `json:"branch"`
+	Base    string    `json:"base"`
+	Title   string    `json:"title"`
+	Body    string    `json:"body,omitempty"`
+	Author  string    `json:"author,omitempty"`
+	State   string    `json:"state,omitempty"`
+	Created time.Time `json:"created"`
+}
+
+func init() {
+	// Local paths and file:// URLs don't have a host.
+	registerUpstream(NewGit(Options{}), "")
+}
+
+// NewGit creates a Git upstream that pushes submissions to opts.URL.
+func NewGit(opts Options) *Git {
+	return &Git{Remote: opts.URL}
+}
+
+// Auth returns an error as Git upstreams use the credentials of the
+// underlying transport rather than an access token.
+func (g *Git) Auth(path string) (result Guard, err error) {
+	return nil, errors.New("git upstreams don't use access tokens")
+}
+
+// CheckToken returns an error as Git upstreams don't use access tokens.
+func (g *Git) CheckToken(token string) (TokenInfo, error) {
+	return TokenInfo{}, errors.New("git upstreams don't use access tokens")
+}
+
+// HaveWriteAccess guesses whether the user can push to the manifest, as a
+// plain git server can't tell us. Without a separate remote for
+// submissions, they're pushed to the manifest itself, so the user is
+// assumed to have write access, and Ark falls back to submitting changes
+// whose pushes are rejected. A separate remote means changes are reviewed.
+func (g *Git) HaveWriteAccess(token string, repoURL url.URL) (bool, error) {
+	return g.Remote == "", nil
+}
+
+// Fork returns the repository submission branches are pushed to.
+func (g *Git) Fork(token string, repoURL url.URL) (string, error) {
+	return g.remote(repoURL), nil
+}
+
+// OpenPR records the submission on the input branch of the fork by
+// pushing a ref holding its metadata alongside the branch.
+func (g *Git) OpenPR(opts PrOpts) (pr PullRequest, err error) {
+	return g.Submit(opts.Fork.String(), opts)
+}
+
+// Submit records the submission on the input branch of the repository at
+// remote, which is written as the user configured it, such as an scp-like
+// address whose path is relative to their home directory on the server.
+func (g *Git) Submit(remote string, opts PrOpts) (pr PullRequest, err error) {
+	submission := Submission{
+		Branch:  opts.PrBranch,
+		Base:    opts.MainBranch,
+		Title:   opts.PrTitle,
+		Body:    opts.PrBody,
+		State:   PrOpen,
+		Created: time.Now().UTC(),
+	}
+	if opts.Name != "" {
+		submission.Author = opts.Name + " <" + opts.Email + ">"
+	}
+
+	signature := object.Signature{Name: opts.Name, Email: opts.Email, When: submission.Created}
+	err = g.record(remote, opts.Auth, submission, signature)
+	return PullRequest{Branch: opts.PrBranch}, err
+}
+
+// SetState records a new state for a submission to the repository at
+// remote, such as PrMerged once a maintainer merges it.
+func (g *Git) SetState(remote string, auth transport.AuthMethod, submission Submission, state string, signature object.Signature) error {
+	submission.State = state
+	return g.record(remote, auth, submission, signature)
+}
+
+// record pushes a ref holding the metadata of a submission to remoteURL,
+// replacing any previous metadata.
+func (g *Git) record(remoteURL string, auth transport.AuthMethod, submission Submission, signature object.Signature) error {
+	data, err := json.MarshalIndent(submission, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	// Build the metadata commit within an in-memory repository.
+	storer := memory.NewStorage()
+	obj := storer.NewEncodedObject()
+	obj.SetType(plumbing.BlobObject)
+	obj.SetSize(int64(len(data)))
+	w, err := obj.Writer()
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	if err != nil {
+		return err
+	}
+	err = w.Close()
+	if err != nil {
+		return err
+	}
+	blob, err := storer.SetEncodedObject(obj)
+	if err != nil {
+		return err
+	}
+	tree, err := storeEncoded(storer, &object.Tree{
+		Entries: []object.TreeEntry{{Name: submissionFile, Mode: filemode.Regular, Hash: blob}},
+	})
+	if err != nil {
+		return err
+	}
+	commit, err := storeEncoded(storer, &object.Commit{
+		Author:    signature,
+		Committer: signature,
+		Message:   submission.Title,
+		TreeHash:  tree,
+	})
+	if err != nil {
+		return err
+	}
+
+	ref := plumbing.ReferenceName(SubmissionRefPrefix + submission.Branch)
+	err = storer.SetReference(plumbing.NewHashReference(ref, commit))
+	if err != nil {
+		return err
+	}
+
+	remote := git.NewRemote(storer, &config.RemoteConfig{
+		Name: "fork",
+		URLs: []string{remoteURL},
+	})
+	return remote.Push(&git.PushOptions{
+		RemoteName: "fork",
+		RefSpecs:   []config.RefSpec{config.RefSpec("+" + ref + ":" + ref)},
+		Auth:       auth,
+	})
+}
+
+// SearchPrByBranch always reports that no submission was found, so
+// OpenPR records the submission's metadata again whenever its branch
+// is updated.
+func (g *Git) SearchPrByBranch(repoURL url.URL, token, branchName string) (pr PullRequest, err error) {
+	return pr, errors.New("not found")
+}
+
+// PrStatus looks up the recorded state of a submission. Submissions
+// whose metadata was removed without merging them are closed.
+func (g *Git) PrStatus(repoURL url.URL, token string, pr PullRequest) (status PrStatus, err error) {
+	return g.Status(g.remote(repoURL), nil, pr)
+}
+
+// Status looks up the recorded state of a submission to the
+// repository at remote, connecting to it with auth.
+func (g *Git) Status(remote string, auth transport.AuthMethod, pr PullRequest) (status PrStatus, err error) {
+	submissions, err := g.Submissions(remote, auth)
+	if err != nil {
+		return status, err
+	}
+	status.State = PrClosed
+	for _, submission := range submissions {
+		if submission.Branch == pr.Branch {
+			status.State = submission.State
+		}
+	}
+	return status, nil
+}
+
+// Submissions lists the submissions recorded in the repository at
+// remote, whether pending or merged, oldest first.
+func (g *Git) Submissions(remoteURL string, auth transport.AuthMethod) ([]Submission, error) {
+	storer := memory.NewStorage()
+	remote := git.NewRemote(storer, &config.RemoteConfig{
+		Name: "fork",
+		URLs: []string{remoteURL},
+	})
+
+	// Only the metadata commits are fetched, as they have no parents.
+	refSpec := config.RefSpec("+" + SubmissionRefPrefix + "*:" + SubmissionRefPrefix + "*")
+	err := remote.Fetch(&git.FetchOptions{
+		RemoteName: "fork",
+		RefSpecs:   []config.RefSpec{refSpec},
+		Auth:       auth,
+	})
+	if err == git.NoErrAlreadyUpToDate {
+		// Nothing was fetched because no submissions are pending.
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	refs, err := storer.IterReferences()
+	if err != nil {
+		return nil, err
+	}
+	result := []Submission{}
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		if !strings.HasPrefix(ref.Name().String(), SubmissionRefPrefix) {
+			return nil
+		}
+		commit, err := object.GetCommit(storer, ref.Hash())
+		if err != nil {
+			return err
+		}
+		file, err := commit.File(submissionFile)
+		if err != nil {
+			return err
+		}
+		contents, err := file.Contents()
+		if err != nil {
+			return err
+		}
+		submission := Submission{}
+		err = json.Unmarshal([]byte(contents), &submission)
+		if err != nil {
+			return err
+		}
+		// The ref, rather than the file, names the branch.
+		submission.Branch = strings.TrimPrefix(ref.Name().String(), SubmissionRefPrefix)
+		if submission.State == "" {
+			submission.State = PrOpen
+		}
+		result = append(result, submission)
+		return nil
+	})
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Created.Before(result[j].Created)
+	})
+	return result, err
+}
+
+// remote returns the repository submissions to a manifest are pushed to.
+func (g *Git) remote(repoURL url.URL) string {
+	if g.Remote != "" {
+		return g.Remote
+	}
+	return repoURL.String()
+}
+
+// storeEncoded encodes an object into storer and returns its hash.
+func storeEncoded(storer *memory.Storage, obj interface {
+	Encode(plumbing.EncodedObject) error
+}) (plumbing.Hash, error) {
+	encoded := storer.NewEncodedObject()
+	err := obj.Encode(encoded)
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+	return storer.SetEncodedObject(encoded)
+}
@@ -0,0 +1,300 @@
+package upstream
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// Gitea is a wrapper struct for Gitea and Forgejo Upstreams. BaseURL
+// is the root of the instance, such as https://gitea.com, and APIURL
+// the root of its REST API.
+type Gitea struct {
+	BaseURL string
+	APIURL  string
+	Client  *http.Client
+}
+
+type giteaUser struct {
+	Login string `json:"login"`
+}
+
+type giteaRepo struct {
+	ID          int       `json:"id"`
+	HTMLURL     string    `json:"html_url"`
+	CloneURL    string    `json:"clone_url"`
+	Owner       giteaUser `json:"owner"`
+	Permissions struct {
+		Admin bool `json:"admin"`
+		Push  bool `json:"push"`
+	} `json:"permissions"`
+}
+
+type giteaPullRequest struct {
+	Number  int    `json:"number"`
+	HTMLURL string `json:"html_url"`
+	State   string `json:"state"`
+	Merged  bool   `json:"merged"`
+	Head    struct {
+		Ref  string     `json:"ref"`
+		Repo *giteaRepo `json:"repo"`
+	} `json:"head"`
+}
+
+type giteaComment struct {
+	Body      string    `json:"body"`
+	User      giteaUser `json:"user"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type giteaReview struct {
+	Body        string    `json:"body"`
+	State       string    `json:"state"`
+	User        giteaUser `json:"user"`
+	SubmittedAt time.Time `json:"submitted_at"`
+}
+
+// giteaPageSize is the number of pull requests requested at a time.
+const giteaPageSize = 50
+
+func init() {
+	registerUpstream(NewGitea(Options{URL: "https://gitea.com"}), "gitea.com")
+	registerUpstream(NewGitea(Options{URL: "https://codeberg.org"}), "codeberg.org")
+}
+
+// NewGitea creates a Gitea upstream for the instance described by opts.
+func NewGitea(opts Options) *Gitea {
+	if opts.APIURL == "" {
+		opts.APIURL = opts.URL + "/api/v1"
+	}
+	return &Gitea{
+		BaseURL: opts.URL,
+		APIURL:  opts.APIURL,
+	}
+}
+
+// Auth asks the user for an access token, as Gitea doesn't
+// support authorizing a device code.
+func (g *Gitea) Auth(path string) (result Guard, err error) {
+	return NewAccessTokenGuard(
+		g.BaseURL+"/user/settings/applications",
+		g.user,
+	), nil
+}
+
+func (g *Gitea) HaveWriteAccess(token string, repoURL url.URL) (bool, error) {
+	repo := &giteaRepo{}
+	err := g.do("GET", g.APIURL+"/repos/"+giteaRepoPath(repoURL), token, nil, repo)
+	if err != nil {
+		return false, err
+	}
+	return repo.Permissions.Admin || repo.Permissions.Push, nil
+}
+
+func (g *Gitea) Fork(token string, repoURL url.URL) (string, error) {
+	username, err := g.user(token)
+	if err != nil {
+		return "", err
+	}
+
+	// Check for the existence of the fork before attempting to create one
+	name := strings.TrimSuffix(path.Base(repoURL.Path), ".git")
+	fork := &giteaRepo{}
+	err = g.do("GET", g.APIURL+"/repos/"+url.PathEscape(username)+"/"+url.PathEscape(name), token, nil, fork)
+	if err != nil {
+		err = g.do("POST", g.APIURL+"/repos/"+giteaRepoPath(repoURL)+"/forks", token, struct{}{}, fork)
+		if err != nil {
+			return "", err
+		}
+	}
+	return fork.CloneURL, nil
+}
+
+// OpenPR opens a pull request from the input branch of the
+// fork to the destination branch of the origin.
+func (g *Gitea) OpenPR(opts PrOpts) (result PullRequest, err error) {
+	forkOwner := path.Base(path.Dir(opts.Fork.Path))
+
+	pr := map[string]string{
+		"head":  fmt.Sprintf("%s:%s", forkOwner, opts.PrBranch),
+		"base":  opts.MainBranch,
+		"title": opts.PrTitle,
+		"body":  opts.PrBody,
+	}
+	created := &giteaPullRequest{}
+	err = g.do("POST", g.APIURL+"/repos/"+giteaRepoPath(opts.Origin)+"/pulls", opts.Token, pr, created)
+	if err != nil {
+		return result, err
+	}
+	return PullRequest{Number: created.Number, URL: created.HTMLURL, Branch: opts.PrBranch}, nil
+}
+
+// SearchPrByBranch checks to see if there is an existing open pull
+// request based on a specific branch of the user's fork. Pull requests
+// from branches of the same name on other users' forks are ignored.
+func (g *Gitea) SearchPrByBranch(repoURL url.URL, token, branchName string) (pr PullRequest, err error) {
+	username, err := g.user(token)
+	if err != nil {
+		return pr, err
+	}
+
+	for page := 1; ; page++ {
+		params := url.Values{}
+		params.Add("state", "open")
+		params.Add("page", fmt.Sprint(page))
+		params.Add("limit", fmt.Sprint(giteaPageSize))
+
+		result := []giteaPullRequest{}
+		err = g.do("GET", g.APIURL+"/repos/"+giteaRepoPath(repoURL)+"/pulls?"+params.Encode(), token, nil, &result)
+		if err != nil {
+			return pr, err
+		}
+		for _, open := range result {
+			if open.Head.Ref == branchName && open.Head.Repo != nil && open.Head.Repo.Owner.Login == username {
+				return PullRequest{Number: open.Number, URL: open.HTMLURL, Branch: branchName}, nil
+			}
+		}
+		if len(result) < giteaPageSize {
+			return pr, errors.New("not found")
+		}
+	}
+}
+
+// PrStatus checks the state of a pull request and collects the comments
+// and reviews left on it.
+func (g *Gitea) PrStatus(repoURL url.URL, token string, pr PullRequest) (status PrStatus, err error) {
+	endpoint := g.APIURL + "/repos/" + giteaRepoPath(repoURL)
+
+	result := &giteaPullRequest{}
+	err = g.do("GET", fmt.Sprintf("%s/pulls/%d", endpoint, pr.Number), token, nil, result)
+	if err != nil {
+		return status, err
+	}
+	switch {
+	case result.Merged:
+		status.State = PrMerged
+	case result.State == "closed":
+		status.State = PrClosed
+	default:
+		status.State = PrOpen
+	}
+
+	comments := []giteaComment{}
+	err = g.do("GET", fmt.Sprintf("%s/issues/%d/comments", endpoint, pr.Number), token, nil, &comments)
+	if err != nil {
+		return status, err
+	}
+	for _, comment := range comments {
+		status.Comments = append(status.Comments, Comment{
+			Author:  comment.User.Login,
+			Body:    comment.Body,
+			Created: comment.CreatedAt,
+		})
+	}
+
+	reviews := []giteaReview{}
+	err = g.do("GET", fmt.Sprintf("%s/pulls/%d/reviews", endpoint, pr.Number), token, nil, &reviews)
+	if err != nil {
+		return status, err
+	}
+	for _, review := range reviews {
+		body := review.Body
+		if body == "" {
+			// Reviews without a body only approve or request changes.
+			body = strings.ToLower(strings.ReplaceAll(review.State, "_", " "))
+		}
+		status.Comments = append(status.Comments, Comment{
+			Author:  review.User.Login,
+			Body:    body,
+			Created: review.SubmittedAt,
+		})
+	}
+	sortComments(status.Comments)
+	return status, nil
+}
+
+// CheckToken looks up the user a token belongs to. Gitea
+// doesn't report the scopes of a token.
+func (g *Gitea) CheckToken(token string) (TokenInfo, error) {
+	user, err := g.user(token)
+	if err != nil {
+		return TokenInfo{}, err
+	}
+	return TokenInfo{User: user}, nil
+}
+
+// user returns the name of the user a token belongs to.
+func (g *Gitea) user(token string) (string, error) {
+	user := &giteaUser{}
+	err := g.do("GET", g.APIURL+"/user", token, nil, user)
+	return user.Login, err
+}
+
+// do sends a request to a URL of the Gitea instance with any input
+// JSON encoded in the body, and decodes the JSON response into result.
+func (g *Gitea) do(method, endpoint, token string, input, result interface{}) error {
+	var body io.Reader
+	if input != nil {
+		buf, err := json.Marshal(input)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(buf)
+	}
+
+	req, err := http.NewRequest(method, endpoint, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Add("Accept", "application/json")
+	if input != nil {
+		req.Header.Add("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Add("Authorization", "token "+token)
+	}
+
+	client := g.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	buf, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		msg := struct {
+			Message string `json:"message"`
+		}{}
+		json.Unmarshal(buf, &msg)
+		if msg.Message != "" {
+			return fmt.Errorf("gitea: %s: %s", resp.Status, msg.Message)
+		}
+		return fmt.Errorf("gitea: %s", resp.Status)
+	}
+	if len(bytes.TrimSpace(buf)) > 0 && result != nil {
+		return json.Unmarshal(buf, result)
+	}
+	return nil
+}
+
+// giteaRepoPath converts the URL of a repository into the escaped
+// "owner/repo" path used by the Gitea API.
+func giteaRepoPath(repoURL url.URL) string {
+	repo := strings.TrimSuffix(path.Base(repoURL.Path), ".git")
+	owner := path.Base(path.Dir(repoURL.Path))
+	return url.PathEscape(owner) + "/" + url.PathEscape(repo)
+}
@@ -0,0 +1,246 @@
+package upstream
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeGitea is a stand-in for the parts of the Gitea API used by the
+// Gitea upstream. Requests must use the token "secret", which belongs
+// to the user "bob".
+type fakeGitea struct {
+	t    *testing.T
+	lock sync.Mutex
+
+	forked bool
+	pulls  []map[string]interface{}
+	bodies map[string]map[string]string
+}
+
+func newFakeGitea(t *testing.T) (*fakeGitea, *Gitea) {
+	fake := &fakeGitea{t: t, bodies: make(map[string]map[string]string)}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	g := NewGitea(Options{URL: server.URL})
+	g.Client = server.Client()
+	return fake, g
+}
+
+// giteaPull describes an open pull request from a branch of owner's fork.
+func giteaPull(number int, owner, branch string) map[string]interface{} {
+	return map[string]interface{}{
+		"number":   number,
+		"html_url": fmt.Sprintf("https://gitea.example/arken/manifest/pulls/%d", number),
+		"state":    "open",
+		"head": map[string]interface{}{
+			"ref":  branch,
+			"repo": map[string]interface{}{"owner": map[string]string{"login": owner}},
+		},
+	}
+}
+
+func (f *fakeGitea) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if r.Header.Get("Authorization") != "token secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"message":"token is required"}`)
+		return
+	}
+	route := r.Method + " " + r.URL.EscapedPath()
+	if r.Method == "POST" {
+		body := map[string]string{}
+		json.NewDecoder(r.Body).Decode(&body)
+		f.bodies[route] = body
+	}
+
+	reply := func(v interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(v)
+	}
+	fork := map[string]interface{}{
+		"id":        7,
+		"clone_url": "https://gitea.example/bob/manifest.git",
+	}
+
+	switch route {
+	case "GET /api/v1/user":
+		reply(map[string]string{"login": "bob"})
+	case "GET /api/v1/repos/arken/manifest":
+		reply(map[string]interface{}{"permissions": map[string]bool{"admin": false, "push": true}})
+	case "GET /api/v1/repos/arken/readonly":
+		reply(map[string]interface{}{"permissions": map[string]bool{"admin": false, "push": false}})
+	case "GET /api/v1/repos/bob/manifest":
+		if !f.forked {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message":"repository does not exist"}`)
+			return
+		}
+		reply(fork)
+	case "POST /api/v1/repos/arken/manifest/forks":
+		f.forked = true
+		reply(fork)
+	case "POST /api/v1/repos/arken/manifest/pulls":
+		reply(map[string]interface{}{
+			"number":   3,
+			"html_url": "https://gitea.example/arken/manifest/pulls/3",
+			"state":    "open",
+		})
+	case "GET /api/v1/repos/arken/manifest/pulls":
+		// Serve pull requests a page at a time.
+		var page, limit int
+		fmt.Sscan(r.URL.Query().Get("page"), &page)
+		fmt.Sscan(r.URL.Query().Get("limit"), &limit)
+		result := []map[string]interface{}{}
+		for i := (page - 1) * limit; i < page*limit && i < len(f.pulls); i++ {
+			result = append(result, f.pulls[i])
+		}
+		reply(result)
+	case "GET /api/v1/repos/arken/manifest/pulls/3":
+		reply(map[string]interface{}{"number": 3, "state": "closed", "merged": true})
+	case "GET /api/v1/repos/arken/manifest/issues/3/comments":
+		reply([]map[string]interface{}{{
+			"body":       "Looks good",
+			"user":       map[string]string{"login": "alice"},
+			"created_at": "2021-06-01T10:00:00Z",
+		}})
+	case "GET /api/v1/repos/arken/manifest/pulls/3/reviews":
+		reply([]map[string]interface{}{{
+			"body":         "",
+			"state":        "APPROVED",
+			"user":         map[string]string{"login": "carol"},
+			"submitted_at": "2021-06-01T09:00:00Z",
+		}})
+	default:
+		f.t.Errorf("unexpected request %s", route)
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestGiteaCheckToken(t *testing.T) {
+	_, g := newFakeGitea(t)
+
+	info, err := g.CheckToken("secret")
+	if err != nil || info.User != "bob" {
+		t.Errorf("CheckToken = %+v, %v", info, err)
+	}
+	_, err = g.CheckToken("wrong")
+	if err == nil {
+		t.Error("CheckToken accepted an invalid token")
+	}
+}
+
+func TestGiteaHaveWriteAccess(t *testing.T) {
+	_, g := newFakeGitea(t)
+
+	ok, err := g.HaveWriteAccess("secret", mustParseURL(t, "https://gitea.example/arken/manifest.git"))
+	if err != nil || !ok {
+		t.Errorf("HaveWriteAccess = %v, %v, want true", ok, err)
+	}
+	ok, err = g.HaveWriteAccess("secret", mustParseURL(t, "https://gitea.example/arken/readonly"))
+	if err != nil || ok {
+		t.Errorf("HaveWriteAccess = %v, %v, want false without push access", ok, err)
+	}
+}
+
+func TestGiteaFork(t *testing.T) {
+	fake, g := newFakeGitea(t)
+
+	// A fork is created the first time, then reused.
+	for i := 0; i < 2; i++ {
+		fork, err := g.Fork("secret", mustParseURL(t, "https://gitea.example/arken/manifest"))
+		if err != nil || fork != "https://gitea.example/bob/manifest.git" {
+			t.Errorf("Fork = %s, %v", fork, err)
+		}
+	}
+	if !fake.forked {
+		t.Error("the fork was never created")
+	}
+}
+
+func TestGiteaOpenPR(t *testing.T) {
+	fake, g := newFakeGitea(t)
+
+	pr, err := g.OpenPR(PrOpts{
+		Origin:     mustParseURL(t, "https://gitea.example/arken/manifest"),
+		Fork:       mustParseURL(t, "https://gitea.example/bob/manifest.git"),
+		Token:      "secret",
+		MainBranch: "main",
+		PrBranch:   "submit/bob/docs/books.ks",
+		PrTitle:    "Add books",
+		PrBody:     "Some books",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := PullRequest{
+		Number: 3,
+		URL:    "https://gitea.example/arken/manifest/pulls/3",
+		Branch: "submit/bob/docs/books.ks",
+	}
+	if pr != want {
+		t.Errorf("OpenPR = %+v, want %+v", pr, want)
+	}
+
+	body := fake.bodies["POST /api/v1/repos/arken/manifest/pulls"]
+	wantBody := map[string]string{
+		"head":  "bob:submit/bob/docs/books.ks",
+		"base":  "main",
+		"title": "Add books",
+		"body":  "Some books",
+	}
+	if !reflect.DeepEqual(body, wantBody) {
+		t.Errorf("pull request = %v, want %v", body, wantBody)
+	}
+}
+
+func TestGiteaSearchPrByBranch(t *testing.T) {
+	fake, g := newFakeGitea(t)
+	repo := mustParseURL(t, "https://gitea.example/arken/manifest")
+
+	// Another user's pull request from a branch with the same name
+	// isn't the user's own.
+	fake.pulls = append(fake.pulls, giteaPull(1, "alice", "submit/books.ks"))
+	_, err := g.SearchPrByBranch(repo, "secret", "submit/books.ks")
+	if err == nil {
+		t.Error("SearchPrByBranch found another user's pull request")
+	}
+
+	// The user's own pull request is found on a later page.
+	for i := 0; i < giteaPageSize; i++ {
+		fake.pulls = append(fake.pulls, giteaPull(10+i, "alice", fmt.Sprintf("submit/%d.ks", i)))
+	}
+	fake.pulls = append(fake.pulls, giteaPull(2, "bob", "submit/books.ks"))
+	pr, err := g.SearchPrByBranch(repo, "secret", "submit/books.ks")
+	want := PullRequest{Number: 2, URL: "https://gitea.example/arken/manifest/pulls/2", Branch: "submit/books.ks"}
+	if err != nil || pr != want {
+		t.Errorf("SearchPrByBranch = %+v, %v, want %+v", pr, err, want)
+	}
+}
+
+func TestGiteaPrStatus(t *testing.T) {
+	_, g := newFakeGitea(t)
+
+	status, err := g.PrStatus(mustParseURL(t, "https://gitea.example/arken/manifest"), "secret", PullRequest{Number: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := PrStatus{
+		State: PrMerged,
+		Comments: []Comment{
+			{Author: "carol", Body: "approved", Created: time.Date(2021, 6, 1, 9, 0, 0, 0, time.UTC)},
+			{Author: "alice", Body: "Looks good", Created: time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)},
+		},
+	}
+	if !reflect.DeepEqual(status, want) {
+		t.Errorf("PrStatus = %+v, want %+v", status, want)
+	}
+}
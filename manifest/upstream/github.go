@@ -7,6 +7,7 @@ import (
 	"net/http"
 	"net/url"
 	"path/filepath"
+	"strings"
 
 	"github.com/google/go-github/v38/github"
 	"golang.org/x/oauth2"
@@ -16,8 +17,13 @@ var (
 	GitHubClientID string
 )
 
-// GitHub is a wrapper struct for the GitHub Upstream
+// GitHub is a wrapper struct for the GitHub Upstream. BaseURL is the
+// root of the website, used for OAuth, and APIURL the root of the REST
+// API, so GitHub Enterprise instances can be used too.
 type GitHub struct {
+	BaseURL  string
+	APIURL   string
+	ClientID string
 }
 
 type GitHubAppAuthQuery struct {
@@ -29,27 +35,67 @@ type GitHubAppAuthQuery struct {
 }
 
 func init() {
-	registerUpstream(&GitHub{}, "github.com")
+	registerUpstream(NewGitHub(Options{URL: "https://github.com"}), "github.com")
+}
+
+// NewGitHub creates a GitHub upstream for the instance described by opts.
+func NewGitHub(opts Options) *GitHub {
+	if opts.APIURL == "" {
+		opts.APIURL = opts.URL + "/api/v3"
+		if opts.URL == "https://github.com" {
+			opts.APIURL = "https://api.github.com"
+		}
+	}
+	return &GitHub{
+		BaseURL:  opts.URL,
+		APIURL:   opts.APIURL,
+		ClientID: opts.ClientID,
+	}
+}
+
+// clientID returns the OAuth application used to authorize devices.
+func (g *GitHub) clientID() string {
+	if g.ClientID != "" {
+		return g.ClientID
+	}
+	return GitHubClientID
+}
+
+// newClient creates a GitHub API client authenticated with
+// token, or an anonymous client if token is empty.
+func (g *GitHub) newClient(token string) *github.Client {
+	var httpClient *http.Client
+	if token != "" {
+		tokenSource := oauth2.StaticTokenSource(
+			&oauth2.Token{AccessToken: token},
+		)
+		httpClient = oauth2.NewClient(context.Background(), tokenSource)
+	}
+	client := github.NewClient(httpClient)
+	if apiURL, err := url.Parse(strings.TrimSuffix(g.APIURL, "/") + "/"); err == nil && g.APIURL != "" {
+		client.BaseURL = apiURL
+	}
+	return client
 }
 
 func (g *GitHub) Auth(path string) (result Guard, err error) {
-	// Check if GitHubClientID has not been set.
-	if GitHubClientID == "" {
+	// Check if the client id has not been set.
+	if g.clientID() == "" {
 		return nil, errors.New("required client id is nil")
 	}
 
-	client := github.NewClient(nil)
+	client := g.newClient("")
 	ctx := context.Background()
 	defer ctx.Done()
 	query := &GitHubAppAuthQuery{}
 
 	// Construct GitHub HTTP query
-	req, _ := http.NewRequest("POST", "https://github.com/login/device/code", nil)
+	req, _ := http.NewRequest("POST", g.BaseURL+"/login/device/code", nil)
 	req.Header.Add("Accept", "application/json")
 
 	// Add parameters to request query
 	params := req.URL.Query()
-	params.Add("client_id", GitHubClientID)
+	params.Add("client_id", g.clientID())
 	params.Add("scope", "public_repo")
 
 	// Encode query
@@ -62,6 +108,7 @@ func (g *GitHub) Auth(path string) (result Guard, err error) {
 	}
 
 	return &GitHubGuard{
+			github: g,
 			client: client,
 			query:  query,
 		},
@@ -69,6 +116,7 @@ func (g *GitHub) Auth(path string) (result Guard, err error) {
 }
 
 type GitHubGuard struct {
+	github *GitHub
 	client *github.Client
 	query  *GitHubAppAuthQuery
 	token  string
@@ -89,6 +137,10 @@ func (g *GitHubGuard) GetCode() string {
 	return g.query.UserCode
 }
 
+func (g *GitHubGuard) GetVerificationURL() string {
+	return g.query.VerificationUri
+}
+
 func (g *GitHubGuard) GetExpireInterval() int {
 	return g.query.ExpiresIn
 }
@@ -102,12 +154,12 @@ func (g *GitHubGuard) CheckStatus() (status string, err error) {
 	defer ctx.Done()
 
 	// Construct poll request.
-	pollReq, _ := http.NewRequest("POST", "https://github.com/login/oauth/access_token", nil)
+	pollReq, _ := http.NewRequest("POST", g.github.BaseURL+"/login/oauth/access_token", nil)
 	pollReq.Header.Add("Accept", "application/json")
 
 	// Add parameters to poll request.
 	params := pollReq.URL.Query()
-	params.Add("client_id", GitHubClientID)
+	params.Add("client_id", g.github.clientID())
 	params.Add("device_code", g.query.DeviceCode)
 	params.Add("grant_type", "urn:ietf:params:oauth:grant-type:device_code")
 
@@ -132,25 +184,7 @@ func (g *GitHubGuard) CheckStatus() (status string, err error) {
 }
 
 func (g *GitHubGuard) GetUser() (string, error) {
-	ctx := context.Background()
-	defer ctx.Done()
-
-	tokenSource := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: g.token},
-	)
-	client := github.NewClient(oauth2.NewClient(ctx, tokenSource))
-
-	// Construct user request to get the name of the current logged in user.
-	req, _ := http.NewRequest("GET", "https://api.github.com/user", nil)
-	user := &github.User{}
-
-	// Launch request
-	_, err := client.Do(ctx, req, user)
-	if err != nil {
-		return "", err
-	}
-
-	return *user.Login, nil
+	return getUsername(g.github.newClient(g.token))
 }
 
 func (g *GitHub) HaveWriteAccess(token string, url url.URL) (bool, error) {
@@ -158,10 +192,7 @@ func (g *GitHub) HaveWriteAccess(token string, url url.URL) (bool, error) {
 	ctx := context.Background()
 	defer ctx.Done()
 
-	tokenSource := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: token},
-	)
-	client := github.NewClient(oauth2.NewClient(ctx, tokenSource))
+	client := g.newClient(token)
 
 	username, err := getUsername(client)
 	if err != nil {
@@ -186,10 +217,7 @@ func (g *GitHub) Fork(token string, url url.URL) (string, error) {
 	ctx := context.Background()
 	defer ctx.Done()
 
-	tokenSource := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: token},
-	)
-	client := github.NewClient(oauth2.NewClient(ctx, tokenSource))
+	client := g.newClient(token)
 
 	username, err := getUsername(client)
 	if err != nil {
@@ -221,27 +249,31 @@ func getUsername(client *github.Client) (string, error) {
 	ctx := context.Background()
 	defer ctx.Done()
 
-	// Construct user request to get the name of the current logged in user.
-	req, _ := http.NewRequest("GET", "https://api.github.com/user", nil)
-	user := &github.User{}
-
-	// Launch request
-	_, err := client.Do(ctx, req, user)
+	// Get the name of the current logged in user.
+	user, _, err := client.Users.Get(ctx, "")
 	if err != nil {
 		return "", err
 	}
-	return *user.Login, nil
+	return user.GetLogin(), nil
+}
+
+// CheckToken looks up the user a token belongs to, along with the
+// scopes GitHub reports for classic and OAuth tokens.
+func (g *GitHub) CheckToken(token string) (TokenInfo, error) {
+	user, resp, err := g.newClient(token).Users.Get(context.Background(), "")
+	if err != nil {
+		return TokenInfo{}, err
+	}
+	return TokenInfo{
+		User:   user.GetLogin(),
+		Scopes: splitScopes(resp.Header.Get("X-OAuth-Scopes")),
+	}, nil
 }
 
 // OpenPR opens a pull request from the input branch to the destination branch.
-func (g *GitHub) OpenPR(opts PrOpts) (err error) {
+func (g *GitHub) OpenPR(opts PrOpts) (result PullRequest, err error) {
 	ctx := context.Background()
-	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: opts.Token},
-	)
-	tc := oauth2.NewClient(ctx, ts)
-
-	client := github.NewClient(tc)
+	client := g.newClient(opts.Token)
 
 	forkOwner := filepath.Base(filepath.Dir(opts.Fork.Path))
 
@@ -256,20 +288,22 @@ func (g *GitHub) OpenPR(opts PrOpts) (err error) {
 	repoOwner := filepath.Base(filepath.Dir(opts.Origin.Path))
 	repoName := filepath.Base(opts.Origin.Path)
 
-	_, _, err = client.PullRequests.Create(ctx, repoOwner, repoName, pr)
-	return err
+	created, _, err := client.PullRequests.Create(ctx, repoOwner, repoName, pr)
+	if err != nil {
+		return PullRequest{}, err
+	}
+	return PullRequest{
+		Number: created.GetNumber(),
+		URL:    created.GetHTMLURL(),
+		Branch: opts.PrBranch,
+	}, nil
 }
 
 // SearchPrByBranch checks to see if there is an existing PR based on a specific branch
-// and if so returns the name.
-func (g *GitHub) SearchPrByBranch(url url.URL, token, branchName string) (err error) {
+// and if so returns it.
+func (g *GitHub) SearchPrByBranch(url url.URL, token, branchName string) (pr PullRequest, err error) {
 	ctx := context.Background()
-	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: token},
-	)
-	tc := oauth2.NewClient(ctx, ts)
-
-	client := github.NewClient(tc)
+	client := g.newClient(token)
 
 	result, _, err := client.Search.Issues(
 		ctx,
@@ -280,14 +314,72 @@ func (g *GitHub) SearchPrByBranch(url url.URL, token, branchName string) (err er
 			filepath.Base(url.Path),
 		), &github.SearchOptions{})
 	if err != nil {
-		return err
+		return pr, err
 	}
 	if len(result.Issues) > 0 {
 		for _, issue := range result.Issues {
 			if issue.GetState() == "open" {
-				return nil
+				return PullRequest{
+					Number: issue.GetNumber(),
+					URL:    issue.GetHTMLURL(),
+					Branch: branchName,
+				}, nil
 			}
 		}
 	}
-	return errors.New("not found")
+	return pr, errors.New("not found")
+}
+
+// PrStatus checks the state of a pull request and collects the comments
+// and reviews left on it.
+func (g *GitHub) PrStatus(url url.URL, token string, pr PullRequest) (status PrStatus, err error) {
+	ctx := context.Background()
+	client := g.newClient(token)
+
+	repoOwner := filepath.Base(filepath.Dir(url.Path))
+	repoName := strings.TrimSuffix(filepath.Base(url.Path), ".git")
+
+	result, _, err := client.PullRequests.Get(ctx, repoOwner, repoName, pr.Number)
+	if err != nil {
+		return status, err
+	}
+	switch {
+	case result.GetMerged():
+		status.State = PrMerged
+	case result.GetState() == "closed":
+		status.State = PrClosed
+	default:
+		status.State = PrOpen
+	}
+
+	comments, _, err := client.Issues.ListComments(ctx, repoOwner, repoName, pr.Number, nil)
+	if err != nil {
+		return status, err
+	}
+	for _, comment := range comments {
+		status.Comments = append(status.Comments, Comment{
+			Author:  comment.GetUser().GetLogin(),
+			Body:    comment.GetBody(),
+			Created: comment.GetCreatedAt(),
+		})
+	}
+
+	reviews, _, err := client.PullRequests.ListReviews(ctx, repoOwner, repoName, pr.Number, nil)
+	if err != nil {
+		return status, err
+	}
+	for _, review := range reviews {
+		body := review.GetBody()
+		if body == "" {
+			// Reviews without a body only approve or request changes.
+			body = strings.ToLower(strings.ReplaceAll(review.GetState(), "_", " "))
+		}
+		status.Comments = append(status.Comments, Comment{
+			Author:  review.GetUser().GetLogin(),
+			Body:    body,
+			Created: review.GetSubmittedAt(),
+		})
+	}
+	sortComments(status.Comments)
+	return status, nil
 }
@@ -0,0 +1,455 @@
+package upstream
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+var (
+	GitLabClientID string
+)
+
+// GitLab is a wrapper struct for the GitLab Upstream. BaseURL is the
+// root of the GitLab instance, such as https://gitlab.com, and APIURL
+// the root of its REST API.
+type GitLab struct {
+	BaseURL  string
+	APIURL   string
+	ClientID string
+	Client   *http.Client
+}
+
+type GitLabAppAuthQuery struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationUri         string `json:"verification_uri"`
+	VerificationUriComplete string `json:"verification_uri_complete"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+type GitLabAppAuthPoll struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	Scope       string `json:"scope"`
+	Error       string `json:"error"`
+}
+
+type gitLabUser struct {
+	ID       int    `json:"id"`
+	Username string `json:"username"`
+}
+
+type gitLabProject struct {
+	ID            int    `json:"id"`
+	WebURL        string `json:"web_url"`
+	HTTPURLToRepo string `json:"http_url_to_repo"`
+	ImportStatus  string `json:"import_status"`
+	ImportError   string `json:"import_error"`
+	Permissions   struct {
+		ProjectAccess *gitLabAccess `json:"project_access"`
+		GroupAccess   *gitLabAccess `json:"group_access"`
+	} `json:"permissions"`
+}
+
+type gitLabAccess struct {
+	AccessLevel int `json:"access_level"`
+}
+
+type gitLabMergeRequest struct {
+	IID    int    `json:"iid"`
+	WebURL string `json:"web_url"`
+	State  string `json:"state"`
+}
+
+type gitLabNote struct {
+	Body      string     `json:"body"`
+	System    bool       `json:"system"`
+	CreatedAt time.Time  `json:"created_at"`
+	Author    gitLabUser `json:"author"`
+}
+
+// gitLabMaintainer is the access level needed to push to a
+// project's protected default branch.
+const gitLabMaintainer = 40
+
+var (
+	// gitLabForkInterval is how often a new fork is checked while
+	// GitLab copies the repository into it.
+	gitLabForkInterval = 2 * time.Second
+	// gitLabForkTimeout is how long to wait for a new fork to be ready.
+	gitLabForkTimeout = 5 * time.Minute
+)
+
+func init() {
+	registerUpstream(NewGitLab(Options{URL: "https://gitlab.com"}), "gitlab.com")
+}
+
+// NewGitLab creates a GitLab upstream for the instance described by opts.
+func NewGitLab(opts Options) *GitLab {
+	if opts.APIURL == "" {
+		opts.APIURL = opts.URL + "/api/v4"
+	}
+	return &GitLab{
+		BaseURL:  opts.URL,
+		APIURL:   opts.APIURL,
+		ClientID: opts.ClientID,
+	}
+}
+
+// clientID returns the OAuth application used to authorize devices.
+func (g *GitLab) clientID() string {
+	if g.ClientID != "" {
+		return g.ClientID
+	}
+	return GitLabClientID
+}
+
+func (g *GitLab) Auth(path string) (result Guard, err error) {
+	// Fall back to a personal access token if the client id has not been set.
+	if g.clientID() == "" {
+		return NewAccessTokenGuard(
+			g.BaseURL+"/-/profile/personal_access_tokens",
+			func(token string) (string, error) {
+				user, err := g.user(token)
+				if err != nil {
+					return "", err
+				}
+				return user.Username, nil
+			},
+		), nil
+	}
+
+	query := &GitLabAppAuthQuery{}
+
+	// Construct GitLab device authorization query
+	params := url.Values{}
+	params.Add("client_id", g.clientID())
+	params.Add("scope", "api")
+
+	err = g.do("POST", g.BaseURL+"/oauth/authorize_device", "", params, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return &GitLabGuard{
+			gitlab: g,
+			query:  query,
+		},
+		nil
+}
+
+type GitLabGuard struct {
+	gitlab *GitLab
+	query  *GitLabAppAuthQuery
+	token  string
+}
+
+func (g *GitLabGuard) GetAccessToken() string {
+	return g.token
+}
+
+func (g *GitLabGuard) GetCode() string {
+	return g.query.UserCode
+}
+
+func (g *GitLabGuard) GetVerificationURL() string {
+	return g.query.VerificationUri
+}
+
+func (g *GitLabGuard) GetExpireInterval() int {
+	return g.query.ExpiresIn
+}
+
+func (g *GitLabGuard) GetInterval() int {
+	return g.query.Interval
+}
+
+func (g *GitLabGuard) CheckStatus() (status string, err error) {
+	// Add parameters to poll request.
+	params := url.Values{}
+	params.Add("client_id", g.gitlab.clientID())
+	params.Add("device_code", g.query.DeviceCode)
+	params.Add("grant_type", "urn:ietf:params:oauth:grant-type:device_code")
+
+	// GitLab responds to pending requests with an error status,
+	// so decode the response body either way.
+	pollResp := &GitLabAppAuthPoll{}
+	err = g.gitlab.do("POST", g.gitlab.BaseURL+"/oauth/token", "", params, pollResp)
+	if err != nil && pollResp.Error == "" {
+		return "", err
+	}
+
+	// Set token on successful auth.
+	if pollResp.AccessToken != "" {
+		g.token = pollResp.AccessToken
+	}
+
+	return pollResp.Error, nil
+}
+
+func (g *GitLabGuard) GetUser() (string, error) {
+	user, err := g.gitlab.user(g.token)
+	if err != nil {
+		return "", err
+	}
+	return user.Username, nil
+}
+
+func (g *GitLab) HaveWriteAccess(token string, repoURL url.URL) (bool, error) {
+	project := &gitLabProject{}
+	err := g.do("GET", g.APIURL+"/projects/"+gitLabProjectID(repoURL), token, nil, project)
+	if err != nil {
+		return false, err
+	}
+
+	// Use the higher of the user's project and group access levels.
+	level := 0
+	for _, access := range []*gitLabAccess{project.Permissions.ProjectAccess, project.Permissions.GroupAccess} {
+		if access != nil && access.AccessLevel > level {
+			level = access.AccessLevel
+		}
+	}
+	return level >= gitLabMaintainer, nil
+}
+
+func (g *GitLab) Fork(token string, repoURL url.URL) (string, error) {
+	user, err := g.user(token)
+	if err != nil {
+		return "", err
+	}
+
+	// Check for the existence of the fork before attempting to create one
+	name := strings.TrimSuffix(path.Base(repoURL.Path), ".git")
+	fork := &gitLabProject{}
+	err = g.do("GET", g.APIURL+"/projects/"+url.PathEscape(user.Username+"/"+name), token, nil, fork)
+	if err != nil {
+		err = g.do("POST", g.APIURL+"/projects/"+gitLabProjectID(repoURL)+"/fork", token, nil, fork)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	// Forks are created in the background, so wait until the repository
+	// has been copied before anything is pushed to it.
+	deadline := time.Now().Add(gitLabForkTimeout)
+	for {
+		switch fork.ImportStatus {
+		case "", "none", "finished":
+			return fork.HTTPURLToRepo, nil
+		case "failed":
+			return "", fmt.Errorf("gitlab: creating the fork failed: %s", fork.ImportError)
+		}
+		if time.Now().After(deadline) {
+			return "", errors.New("gitlab: timed out waiting for the fork to be created")
+		}
+		time.Sleep(gitLabForkInterval)
+
+		err = g.do("GET", fmt.Sprintf("%s/projects/%d", g.APIURL, fork.ID), token, nil, fork)
+		if err != nil {
+			return "", err
+		}
+	}
+}
+
+// OpenPR opens a merge request from the input branch of the
+// fork to the destination branch of the origin.
+func (g *GitLab) OpenPR(opts PrOpts) (pr PullRequest, err error) {
+	// Merge requests from a fork target the origin project by its ID.
+	origin := &gitLabProject{}
+	err = g.do("GET", g.APIURL+"/projects/"+gitLabProjectID(opts.Origin), opts.Token, nil, origin)
+	if err != nil {
+		return pr, err
+	}
+
+	params := url.Values{}
+	params.Add("source_branch", opts.PrBranch)
+	params.Add("target_branch", opts.MainBranch)
+	params.Add("target_project_id", fmt.Sprint(origin.ID))
+	params.Add("title", opts.PrTitle)
+	params.Add("description", opts.PrBody)
+	params.Add("allow_collaboration", "true")
+
+	mr := &gitLabMergeRequest{}
+	err = g.do("POST", g.APIURL+"/projects/"+gitLabProjectID(opts.Fork)+"/merge_requests", opts.Token, params, mr)
+	if err != nil {
+		return pr, err
+	}
+	return PullRequest{Number: mr.IID, URL: mr.WebURL, Branch: opts.PrBranch}, nil
+}
+
+// SearchPrByBranch checks to see if there is an existing open merge
+// request opened by the user from a specific branch. Merge requests
+// from branches of the same name opened by other users are ignored.
+func (g *GitLab) SearchPrByBranch(repoURL url.URL, token, branchName string) (pr PullRequest, err error) {
+	user, err := g.user(token)
+	if err != nil {
+		return pr, err
+	}
+
+	params := url.Values{}
+	params.Add("state", "opened")
+	params.Add("source_branch", branchName)
+	params.Add("author_username", user.Username)
+
+	result := []gitLabMergeRequest{}
+	err = g.do("GET", g.APIURL+"/projects/"+gitLabProjectID(repoURL)+"/merge_requests?"+params.Encode(), token, nil, &result)
+	if err != nil {
+		return pr, err
+	}
+	if len(result) > 0 {
+		return PullRequest{Number: result[0].IID, URL: result[0].WebURL, Branch: branchName}, nil
+	}
+	return pr, errors.New("not found")
+}
+
+// PrStatus checks the state of a merge request and collects the
+// comments left on it, skipping notes GitLab adds itself.
+func (g *GitLab) PrStatus(repoURL url.URL, token string, pr PullRequest) (status PrStatus, err error) {
+	endpoint := fmt.Sprintf("%s/projects/%s/merge_requests/%d", g.APIURL, gitLabProjectID(repoURL), pr.Number)
+
+	mr := &gitLabMergeRequest{}
+	err = g.do("GET", endpoint, token, nil, mr)
+	if err != nil {
+		return status, err
+	}
+	switch mr.State {
+	case "merged":
+		status.State = PrMerged
+	case "closed", "locked":
+		status.State = PrClosed
+	default:
+		status.State = PrOpen
+	}
+
+	params := url.Values{}
+	params.Add("sort", "asc")
+	params.Add("per_page", "100")
+
+	notes := []gitLabNote{}
+	err = g.do("GET", endpoint+"/notes?"+params.Encode(), token, nil, &notes)
+	if err != nil {
+		return status, err
+	}
+	for _, note := range notes {
+		if note.System {
+			continue
+		}
+		status.Comments = append(status.Comments, Comment{
+			Author:  note.Author.Username,
+			Body:    note.Body,
+			Created: note.CreatedAt,
+		})
+	}
+	return status, nil
+}
+
+// CheckToken looks up the user a token belongs to and its scopes,
+// which GitLab reports differently for personal access tokens and
+// OAuth tokens.
+func (g *GitLab) CheckToken(token string) (TokenInfo, error) {
+	user, err := g.user(token)
+	if err != nil {
+		return TokenInfo{}, err
+	}
+	info := TokenInfo{User: user.Username}
+
+	scopes := struct {
+		Scopes []string `json:"scopes"`
+		Scope  []string `json:"scope"`
+	}{}
+	err = g.do("GET", g.APIURL+"/personal_access_tokens/self", token, nil, &scopes)
+	if err != nil {
+		err = g.do("GET", g.BaseURL+"/oauth/token/info", token, nil, &scopes)
+	}
+	if err == nil {
+		info.Scopes = append(scopes.Scopes, scopes.Scope...)
+	}
+	return info, nil
+}
+
+// user returns the user a token belongs to.
+func (g *GitLab) user(token string) (*gitLabUser, error) {
+	user := &gitLabUser{}
+	err := g.do("GET", g.APIURL+"/user", token, nil, user)
+	return user, err
+}
+
+// do sends a request to a URL of the GitLab instance with any parameters
+// form encoded in the body, and decodes the JSON response into result.
+func (g *GitLab) do(method, endpoint, token string, params url.Values, result interface{}) error {
+	var body io.Reader
+	if params != nil {
+		body = strings.NewReader(params.Encode())
+	}
+
+	req, err := http.NewRequest(method, endpoint, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Add("Accept", "application/json")
+	if params != nil {
+		req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+	}
+	if token != "" {
+		req.Header.Add("Authorization", "Bearer "+token)
+	}
+
+	client := g.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	buf, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if len(bytes.TrimSpace(buf)) > 0 && result != nil {
+		// Error responses may not match the result, so only
+		// report decoding failures for successful requests.
+		decodeErr := json.Unmarshal(buf, result)
+		if decodeErr != nil && resp.StatusCode < 300 {
+			return decodeErr
+		}
+	}
+
+	if resp.StatusCode >= 300 {
+		return gitLabError(resp.Status, buf)
+	}
+	return nil
+}
+
+// gitLabError builds an error from the body of a failed GitLab request.
+func gitLabError(status string, body []byte) error {
+	msg := struct {
+		Message interface{} `json:"message"`
+		Error   string      `json:"error"`
+	}{}
+	json.Unmarshal(body, &msg)
+	switch {
+	case msg.Message != nil:
+		return fmt.Errorf("gitlab: %s: %v", status, msg.Message)
+	case msg.Error != "":
+		return fmt.Errorf("gitlab: %s: %s", status, msg.Error)
+	}
+	return fmt.Errorf("gitlab: %s", status)
+}
+
+// gitLabProjectID converts the URL of a repository into the
+// escaped "namespace/project" ID used by the GitLab API.
+func gitLabProjectID(repoURL url.URL) string {
+	return url.PathEscape(strings.TrimSuffix(strings.Trim(repoURL.Path, "/"), ".git"))
+}
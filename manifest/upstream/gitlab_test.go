@@ -0,0 +1,311 @@
+package upstream
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeGitLab is a stand-in for the parts of the GitLab API used by
+// the GitLab upstream. Requests must use the token "secret".
+type fakeGitLab struct {
+	t    *testing.T
+	lock sync.Mutex
+
+	// importPolls is how many times a new fork reports it is still
+	// being imported before it's finished.
+	importPolls int
+	forked      bool
+	forkPolls   int
+	forms       map[string]url.Values
+	// mergeRequests are the open merge requests on the origin project.
+	mergeRequests []gitLabTestMR
+}
+
+// gitLabTestMR is an open merge request on the fake origin project.
+type gitLabTestMR struct {
+	IID    int
+	Author string
+	Branch string
+}
+
+func newFakeGitLab(t *testing.T) (*fakeGitLab, *GitLab) {
+	fake := &fakeGitLab{t: t, forms: make(map[string]url.Values)}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	g := NewGitLab(Options{URL: server.URL})
+	g.Client = server.Client()
+	return fake, g
+}
+
+func (f *fakeGitLab) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"message":"401 Unauthorized"}`)
+		return
+	}
+	r.ParseForm()
+	route := r.Method + " " + r.URL.EscapedPath()
+	f.forms[route] = r.PostForm
+
+	reply := func(v interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(v)
+	}
+	project := func(id int, importStatus string) map[string]interface{} {
+		return map[string]interface{}{
+			"id":               id,
+			"web_url":          "https://gitlab.example/bob/manifest",
+			"http_url_to_repo": "https://gitlab.example/bob/manifest.git",
+			"import_status":    importStatus,
+		}
+	}
+
+	switch route {
+	case "GET /api/v4/user":
+		reply(map[string]interface{}{"id": 1, "username": "bob"})
+	case "GET /api/v4/personal_access_tokens/self":
+		reply(map[string]interface{}{"scopes": []string{"api", "read_user"}})
+	case "GET /api/v4/projects/arken%2Fmanifest":
+		reply(map[string]interface{}{
+			"id": 42,
+			"permissions": map[string]interface{}{
+				"project_access": map[string]int{"access_level": 30},
+				"group_access":   map[string]int{"access_level": 40},
+			},
+		})
+	case "GET /api/v4/projects/arken%2Fdeveloper":
+		reply(map[string]interface{}{
+			"id": 43,
+			"permissions": map[string]interface{}{
+				"project_access": map[string]int{"access_level": 30},
+			},
+		})
+	case "GET /api/v4/projects/bob%2Fmanifest":
+		if !f.forked {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message":"404 Project Not Found"}`)
+			return
+		}
+		reply(project(7, "finished"))
+	case "POST /api/v4/projects/arken%2Fmanifest/fork":
+		f.forked = true
+		reply(project(7, "scheduled"))
+	case "GET /api/v4/projects/7":
+		f.forkPolls++
+		status := "started"
+		if f.forkPolls > f.importPolls {
+			status = "finished"
+		}
+		reply(project(7, status))
+	case "POST /api/v4/projects/bob%2Fmanifest/merge_requests":
+		reply(map[string]interface{}{
+			"iid":     5,
+			"web_url": "https://gitlab.example/arken/manifest/-/merge_requests/5",
+			"state":   "opened",
+		})
+	case "GET /api/v4/projects/arken%2Fmanifest/merge_requests":
+		query := r.URL.Query()
+		result := []map[string]interface{}{}
+		for _, mr := range f.mergeRequests {
+			if query.Get("state") != "opened" ||
+				query.Get("source_branch") != mr.Branch ||
+				(query.Get("author_username") != "" && query.Get("author_username") != mr.Author) {
+				continue
+			}
+			result = append(result, map[string]interface{}{
+				"iid":     mr.IID,
+				"web_url": fmt.Sprintf("https://gitlab.example/arken/manifest/-/merge_requests/%d", mr.IID),
+				"state":   "opened",
+			})
+		}
+		reply(result)
+	case "GET /api/v4/projects/arken%2Fmanifest/merge_requests/5":
+		reply(map[string]interface{}{"iid": 5, "state": "merged"})
+	case "GET /api/v4/projects/arken%2Fmanifest/merge_requests/5/notes":
+		reply([]map[string]interface{}{
+			{
+				"body":       "Looks good",
+				"system":     false,
+				"created_at": "2021-06-01T10:00:00Z",
+				"author":     map[string]string{"username": "alice"},
+			},
+			{
+				"body":       "merged",
+				"system":     true,
+				"created_at": "2021-06-01T11:00:00Z",
+				"author":     map[string]string{"username": "alice"},
+			},
+		})
+	default:
+		f.t.Errorf("unexpected request %s", route)
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func mustParseURL(t *testing.T, raw string) url.URL {
+	t.Helper()
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return *u
+}
+
+func TestGitLabCheckToken(t *testing.T) {
+	_, g := newFakeGitLab(t)
+
+	info, err := g.CheckToken("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := TokenInfo{User: "bob", Scopes: []string{"api", "read_user"}}
+	if !reflect.DeepEqual(info, want) {
+		t.Errorf("CheckToken = %+v, want %+v", info, want)
+	}
+
+	_, err = g.CheckToken("wrong")
+	if err == nil {
+		t.Error("CheckToken accepted an invalid token")
+	}
+}
+
+func TestGitLabHaveWriteAccess(t *testing.T) {
+	_, g := newFakeGitLab(t)
+
+	// Maintainer access through the group is enough.
+	ok, err := g.HaveWriteAccess("secret", mustParseURL(t, "https://gitlab.example/arken/manifest.git"))
+	if err != nil || !ok {
+		t.Errorf("HaveWriteAccess = %v, %v, want true", ok, err)
+	}
+	ok, err = g.HaveWriteAccess("secret", mustParseURL(t, "https://gitlab.example/arken/developer"))
+	if err != nil || ok {
+		t.Errorf("HaveWriteAccess = %v, %v, want false for a developer", ok, err)
+	}
+}
+
+func TestGitLabFork(t *testing.T) {
+	interval := gitLabForkInterval
+	gitLabForkInterval = time.Millisecond
+	defer func() { gitLabForkInterval = interval }()
+
+	fake, g := newFakeGitLab(t)
+	fake.importPolls = 2
+
+	// A new fork is only returned once GitLab has finished importing it.
+	fork, err := g.Fork("secret", mustParseURL(t, "https://gitlab.example/arken/manifest"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fork != "https://gitlab.example/bob/manifest.git" {
+		t.Errorf("Fork = %s", fork)
+	}
+	if fake.forkPolls != 3 {
+		t.Errorf("the fork was checked %d times, want 3", fake.forkPolls)
+	}
+
+	// An existing fork is used as is.
+	fork, err = g.Fork("secret", mustParseURL(t, "https://gitlab.example/arken/manifest"))
+	if err != nil || fork != "https://gitlab.example/bob/manifest.git" {
+		t.Errorf("Fork = %s, %v", fork, err)
+	}
+	if fake.forkPolls != 3 {
+		t.Errorf("the existing fork was checked again")
+	}
+}
+
+func TestGitLabOpenPR(t *testing.T) {
+	fake, g := newFakeGitLab(t)
+
+	pr, err := g.OpenPR(PrOpts{
+		Origin:     mustParseURL(t, "https://gitlab.example/arken/manifest"),
+		Fork:       mustParseURL(t, "https://gitlab.example/bob/manifest.git"),
+		Token:      "secret",
+		MainBranch: "main",
+		PrBranch:   "submit/books.ks",
+		PrTitle:    "Add books",
+		PrBody:     "Some books",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := PullRequest{
+		Number: 5,
+		URL:    "https://gitlab.example/arken/manifest/-/merge_requests/5",
+		Branch: "submit/books.ks",
+	}
+	if pr != want {
+		t.Errorf("OpenPR = %+v, want %+v", pr, want)
+	}
+
+	form := fake.forms["POST /api/v4/projects/bob%2Fmanifest/merge_requests"]
+	for key, value := range map[string]string{
+		"source_branch":     "submit/books.ks",
+		"target_branch":     "main",
+		"target_project_id": "42",
+		"title":             "Add books",
+		"description":       "Some books",
+	} {
+		if form.Get(key) != value {
+			t.Errorf("merge request %s = %q, want %q", key, form.Get(key), value)
+		}
+	}
+}
+
+func TestGitLabSearchPrByBranch(t *testing.T) {
+	fake, g := newFakeGitLab(t)
+	repo := mustParseURL(t, "https://gitlab.example/arken/manifest")
+
+	// Another user's merge request from a branch with the same name
+	// isn't the user's own.
+	fake.mergeRequests = append(fake.mergeRequests, gitLabTestMR{IID: 1, Author: "alice", Branch: "submit/books.ks"})
+	_, err := g.SearchPrByBranch(repo, "secret", "submit/books.ks")
+	if err == nil {
+		t.Error("SearchPrByBranch found another user's merge request")
+	}
+
+	fake.mergeRequests = append(fake.mergeRequests, gitLabTestMR{IID: 2, Author: "bob", Branch: "submit/books.ks"})
+	pr, err := g.SearchPrByBranch(repo, "secret", "submit/books.ks")
+	want := PullRequest{
+		Number: 2,
+		URL:    "https://gitlab.example/arken/manifest/-/merge_requests/2",
+		Branch: "submit/books.ks",
+	}
+	if err != nil || pr != want {
+		t.Errorf("SearchPrByBranch = %+v, %v, want %+v", pr, err, want)
+	}
+}
+
+func TestGitLabPrStatus(t *testing.T) {
+	_, g := newFakeGitLab(t)
+
+	status, err := g.PrStatus(
+		mustParseURL(t, "https://gitlab.example/arken/manifest"),
+		"secret",
+		PullRequest{Number: 5},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := PrStatus{
+		State: PrMerged,
+		Comments: []Comment{{
+			Author:  "alice",
+			Body:    "Looks good",
+			Created: time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC),
+		}},
+	}
+	if !reflect.DeepEqual(status, want) {
+		t.Errorf("PrStatus = %+v, want %+v", status, want)
+	}
+}
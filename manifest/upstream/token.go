@@ -0,0 +1,58 @@
+package upstream
+
+// TokenGuard is a Guard for upstreams where the user creates an access
+// token themselves, at the Guard's verification URL, instead of
+// authorizing a device code.
+type TokenGuard interface {
+	Guard
+	SetAccessToken(token string)
+}
+
+// AccessTokenGuard is a TokenGuard that checks a token by looking up
+// the user it belongs to.
+type AccessTokenGuard struct {
+	URL   string
+	token string
+	user  func(token string) (string, error)
+}
+
+// NewAccessTokenGuard creates an AccessTokenGuard where tokens are created
+// at url and user returns the name of the user a token belongs to.
+func NewAccessTokenGuard(url string, user func(token string) (string, error)) *AccessTokenGuard {
+	return &AccessTokenGuard{URL: url, user: user}
+}
+
+func (g *AccessTokenGuard) SetAccessToken(token string) {
+	g.token = token
+}
+
+func (g *AccessTokenGuard) GetAccessToken() string {
+	return g.token
+}
+
+func (g *AccessTokenGuard) GetCode() string {
+	return ""
+}
+
+func (g *AccessTokenGuard) GetVerificationURL() string {
+	return g.URL
+}
+
+func (g *AccessTokenGuard) GetExpireInterval() int {
+	return 0
+}
+
+func (g *AccessTokenGuard) GetInterval() int {
+	return 0
+}
+
+// CheckStatus checks that the token belongs to a user. There's
+// nothing to wait for, so a valid token's status is always empty.
+func (g *AccessTokenGuard) CheckStatus() (status string, err error) {
+	_, err = g.user(g.token)
+	return "", err
+}
+
+func (g *AccessTokenGuard) GetUser() (string, error) {
+	return g.user(g.token)
+}
@@ -1,6 +1,14 @@
 package upstream
 
-import "net/url"
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5/plumbing/transport"
+)
 
 var AvailableUpstreams map[string]Upstream
 
@@ -8,32 +16,129 @@ type Upstream interface {
 	Auth(path string) (result Guard, err error)
 	HaveWriteAccess(token string, url url.URL) (hasAccess bool, err error)
 	Fork(token string, url url.URL) (result string, err error)
-	OpenPR(opts PrOpts) (err error)
-	SearchPrByBranch(url url.URL, token, branchName string) (err error)
+	OpenPR(opts PrOpts) (pr PullRequest, err error)
+	SearchPrByBranch(url url.URL, token, branchName string) (pr PullRequest, err error)
+	PrStatus(url url.URL, token string, pr PullRequest) (status PrStatus, err error)
+	CheckToken(token string) (info TokenInfo, err error)
+}
+
+// States of a pull request.
+const (
+	PrOpen   = "open"
+	PrMerged = "merged"
+	PrClosed = "closed"
+)
+
+// PullRequest identifies a pull request opened on an upstream. Upstreams
+// without pull requests identify them by their Branch alone.
+type PullRequest struct {
+	Number int    `json:"number,omitempty"`
+	URL    string `json:"url,omitempty"`
+	Branch string `json:"branch"`
+}
+
+// PrStatus is the state of a pull request, one of PrOpen, PrMerged,
+// or PrClosed, along with the comments left on it by reviewers.
+type PrStatus struct {
+	State    string
+	Comments []Comment
+}
+
+// Comment is a comment or review left on a pull request.
+type Comment struct {
+	Author  string
+	Body    string
+	Created time.Time
+}
+
+// TokenInfo describes the user an access token belongs to and the
+// scopes it grants, for upstreams that report them.
+type TokenInfo struct {
+	User   string
+	Scopes []string
 }
 
 type Guard interface {
 	GetAccessToken() (token string)
 	GetCode() (code string)
+	GetVerificationURL() (url string)
 	GetInterval() (interval int)
 	GetExpireInterval() (interval int)
 	CheckStatus() (status string, err error)
 	GetUser() (username string, err error)
 }
 
+// PrOpts describe a pull request. Name and Email identify the user
+// opening it, and Auth is the transport auth for the fork, which
+// upstreams that push to it directly use.
 type PrOpts struct {
 	Origin     url.URL
 	Fork       url.URL
 	Token      string
+	Name       string
+	Email      string
+	Auth       transport.AuthMethod
 	MainBranch string
 	PrBranch   string
 	PrTitle    string
 	PrBody     string
 }
 
+// sortComments sorts comments from oldest to newest.
+func sortComments(comments []Comment) {
+	sort.SliceStable(comments, func(i, j int) bool {
+		return comments[i].Created.Before(comments[j].Created)
+	})
+}
+
+// splitScopes splits a comma or space separated list of scopes.
+func splitScopes(scopes string) []string {
+	return strings.FieldsFunc(scopes, func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+}
+
 func registerUpstream(upstream Upstream, host string) {
 	if AvailableUpstreams == nil {
 		AvailableUpstreams = make(map[string]Upstream)
 	}
 	AvailableUpstreams[host] = upstream
 }
+
+// Options describe a specific instance of an upstream. URL is the root
+// of its website and APIURL the root of its REST API, which defaults
+// to the usual location for the upstream's type. ClientID is the OAuth
+// application used to authorize devices, if the instance has one.
+type Options struct {
+	URL      string
+	APIURL   string
+	ClientID string
+}
+
+// Configure registers an upstream of the named type for a host,
+// such as a self-hosted or enterprise instance. For a plain git
+// server, opts.URL is the repository submissions are pushed to.
+func Configure(host, kind string, opts Options) error {
+	if strings.ToLower(kind) == "git" {
+		registerUpstream(NewGit(opts), host)
+		return nil
+	}
+
+	if opts.URL == "" {
+		opts.URL = "https://" + host
+	}
+	opts.URL = strings.TrimSuffix(opts.URL, "/")
+	opts.APIURL = strings.TrimSuffix(opts.APIURL, "/")
+
+	switch strings.ToLower(kind) {
+	case "github":
+		registerUpstream(NewGitHub(opts), host)
+	case "gitlab":
+		registerUpstream(NewGitLab(opts), host)
+	case "gitea", "forgejo":
+		registerUpstream(NewGitea(opts), host)
+	default:
+		return fmt.Errorf("unknown upstream type %q for %s", kind, host)
+	}
+	return nil
+}
@@ -1,23 +1,36 @@
 package manifest
 
 import (
+	"path/filepath"
 	"time"
 
+	"github.com/arken/ark/keyset"
 	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
 	"github.com/go-git/go-git/v5/plumbing/object"
 )
 
-// Commit performs a git commit on the repository.
+// Commit stages the keyset at path, along with its metadata file, and
+// performs a git commit on the repository. Keysets that were removed
+// from disk are removed from the repository.
 func (m *Manifest) Commit(path, commitMessage string) (err error) {
 	w, err := m.r.Worktree()
 	if err != nil {
 		return err
 	}
 
-	_, err = w.Add(".")
+	rel, err := filepath.Rel(m.path, path)
 	if err != nil {
 		return err
 	}
+	for _, file := range []string{rel, rel + keyset.MetaSuffix} {
+		// Adding a missing file stages its removal, unless it was
+		// never part of the repository.
+		_, err = w.Add(filepath.ToSlash(file))
+		if err != nil && err != index.ErrEntryNotFound {
+			return err
+		}
+	}
 
 	commit, err := w.Commit(commitMessage, &git.CommitOptions{
 		Author: &object.Signature{
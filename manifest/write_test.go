@@ -0,0 +1,173 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// testSignature is the identity commits are made with during tests.
+var testSignature = object.Signature{Name: "Test", Email: "test@example.com"}
+
+// newTestRemote creates a bare repository holding files in a single
+// commit on master, and returns its path.
+func newTestRemote(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+
+	src, err := git.PlainInit(filepath.Join(dir, "src"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := src.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, contents := range files {
+		path := filepath.Join(dir, "src", filepath.FromSlash(name))
+		err = os.MkdirAll(filepath.Dir(path), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(path, []byte(contents), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = w.Add(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	signature := testSignature
+	signature.When = time.Now()
+	_, err = w.Commit("Initial commit", &git.CommitOptions{Author: &signature})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bare := filepath.Join(dir, "remote.git")
+	_, err = git.PlainClone(bare, true, &git.CloneOptions{URL: filepath.Join(dir, "src")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bare
+}
+
+// openTestManifest clones the manifest at remote into a new directory.
+func openTestManifest(t *testing.T, remote string) *Manifest {
+	t.Helper()
+	return openTestManifestAs(t, remote, GitOptions{
+		Name:  testSignature.Name,
+		Email: testSignature.Email,
+	})
+}
+
+// openTestManifestAs clones the manifest at remote into a new directory
+// with the identity in opts.
+func openTestManifestAs(t *testing.T, remote string, opts GitOptions) *Manifest {
+	t.Helper()
+	m, err := Init(filepath.Join(t.TempDir(), "manifest"), remote, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// remoteFile returns the contents of a file on a branch of the
+// repository at remote, and whether it exists.
+func remoteFile(t *testing.T, remote, branch, name string) (string, bool) {
+	t.Helper()
+	r, err := git.PlainOpen(remote)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, err := r.Reference(plumbing.NewBranchReferenceName(branch), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit, err := r.CommitObject(ref.Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, err := commit.File(name)
+	if err == object.ErrFileNotFound {
+		return "", false
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	contents, err := file.Contents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return contents, true
+}
+
+func TestCommitRemovedKeyset(t *testing.T) {
+	remote := newTestRemote(t, map[string]string{
+		"config.toml":     "name = \"test\"\n",
+		"docs/a.ks":       "QmT78zSuBmuS4z925WZfrqQ1qHaJ56DQaTfyMUF7F8ff5o  a.txt\n",
+		"docs/a.ks.meta":  "#: title A\n",
+		"docs/keep.ks":    "QmXgm5QVTy8pRtKrTPmoWPGXNesehCpP4jjFMTpvGamc1p  b.txt\n",
+		"docs/other.note": "left alone\n",
+	})
+	m := openTestManifest(t, remote)
+
+	// Retracting the last entry of a keyset removes it and its metadata.
+	path := filepath.Join(m.path, "docs", "a.ks")
+	for _, file := range []string{path, path + ".meta"} {
+		err := os.Remove(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Files other than the keyset aren't part of the commit.
+	err := os.WriteFile(filepath.Join(m.path, "docs", "other.note"), []byte("changed\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = m.Commit(path, "Retract 1 file(s) from docs/a.ks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = m.Push()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"docs/a.ks", "docs/a.ks.meta"} {
+		if _, ok := remoteFile(t, remote, "master", name); ok {
+			t.Errorf("%s is still in the manifest", name)
+		}
+	}
+	if _, ok := remoteFile(t, remote, "master", "docs/keep.ks"); !ok {
+		t.Error("docs/keep.ks was removed from the manifest")
+	}
+	if contents, _ := remoteFile(t, remote, "master", "docs/other.note"); contents != "left alone\n" {
+		t.Errorf("docs/other.note was committed as %q", contents)
+	}
+
+	// A new keyset without a metadata file can be committed too.
+	path = filepath.Join(m.path, "docs", "new.ks")
+	err = os.WriteFile(path, []byte("QmXgm5QVTy8pRtKrTPmoWPGXNesehCpP4jjFMTpvGamc1p  c.txt\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = m.Commit(path, "Add docs/new.ks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = m.Push()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := remoteFile(t, remote, "master", "docs/new.ks"); !ok {
+		t.Error("docs/new.ks wasn't pushed to the manifest")
+	}
+}
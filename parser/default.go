@@ -25,6 +25,25 @@ var SubmissionTemplate = `# Note: Any lines that start with "#" are what we call
 # TITLE below
 
 
+# (Optional) Who created the files you're submitting?
+# AUTHOR below
+
+
+# (Optional) Where can the original files be found?
+# This line should be a URL.
+# SOURCE below
+
+
+# (Optional) The SPDX license identifier the files are released under.
+# For example,
+# CC-BY-4.0
+# LICENSE below
+
+
+# (Optional) A short description stored alongside each file.
+# DESCRIPTION below
+
+
 # An empty commit message will abort the submission.
 # Describe the files in more detail.
 # COMMIT below
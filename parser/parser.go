@@ -3,20 +3,35 @@ package parser
 import (
 	"bufio"
 	"errors"
+	"net/url"
 	"path/filepath"
 	"strings"
 )
 
 // Application is a struct holding the application fields.
 type Application struct {
-	Title    string
-	Commit   string
-	PRBody   string
-	Category string
-	Filename string
+	Title       string
+	Commit      string
+	PRBody      string
+	Category    string
+	Filename    string
+	Author      string
+	Source      string
+	License     string
+	Description string
 }
 
+// ParseApplication reads the fields of a submission application
+// and validates the result.
 func ParseApplication(input string) (Application, error) {
+	app := ReadApplication(input)
+	err := app.Validate()
+	return app, err
+}
+
+// ReadApplication fills out an Application from the contents of a
+// submission template without validating the resulting fields.
+func ReadApplication(input string) Application {
 	app := Application{}
 
 	scanner := bufio.NewScanner(strings.NewReader(input))
@@ -37,22 +52,59 @@ func ParseApplication(input string) (Application, error) {
 			ptr = &app.Commit
 		} else if strings.HasPrefix(line, "# PULL REQUEST") {
 			ptr = &app.PRBody
+		} else if strings.HasPrefix(line, "# AUTHOR") {
+			ptr = &app.Author
+		} else if strings.HasPrefix(line, "# SOURCE") {
+			ptr = &app.Source
+		} else if strings.HasPrefix(line, "# LICENSE") {
+			ptr = &app.License
+		} else if strings.HasPrefix(line, "# DESCRIPTION") {
+			ptr = &app.Description
 		}
 	}
 
+	return app
+}
+
+// Validate normalizes the fields of an Application and checks
+// that they describe a usable submission.
+func (app *Application) Validate() error {
 	// Trim whitespace from around fields
-	app.Category = strings.TrimSpace(filepath.Clean(app.Category))
+	app.Category = filepath.Clean(strings.TrimSpace(app.Category))
 	app.Commit = strings.TrimSpace(app.Commit)
 	app.Filename = strings.TrimSpace(app.Filename)
 	app.PRBody = strings.TrimSpace(app.PRBody)
 	app.Title = strings.TrimSpace(app.Title)
+	app.Author = strings.TrimSpace(app.Author)
+	app.Source = strings.TrimSpace(app.Source)
+	app.License = strings.TrimSpace(app.License)
+	app.Description = strings.TrimSpace(app.Description)
 
+	if app.Filename == "" || app.Filename == ".ks" {
+		return errors.New("a filename is required for the keyset")
+	}
+	if strings.ContainsRune(app.Filename, filepath.Separator) {
+		return errors.New("the keyset filename cannot contain a path separator")
+	}
 	if !strings.HasSuffix(app.Filename, ".ks") {
 		app.Filename += ".ks"
 	}
 
 	if strings.Contains(app.Category, "..") {
-		return app, errors.New("path backtracking (\"..\") is not allowed in the category")
+		return errors.New("path backtracking (\"..\") is not allowed in the category")
+	}
+
+	if app.Source != "" {
+		u, err := url.Parse(app.Source)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return errors.New("the source must be an absolute URL")
+		}
+	}
+	if strings.ContainsAny(app.License, "\n\t") {
+		return errors.New("the license must be a single SPDX identifier or expression")
+	}
+	if app.Commit == "" {
+		return errors.New("an empty commit message aborts the submission")
 	}
-	return app, nil
+	return nil
 }
@@ -0,0 +1,171 @@
+package stage
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"sort"
+	"time"
+)
+
+const (
+	// IndexPath is the location of the staging index.
+	IndexPath string = ".ark/index.json"
+	// LegacyPath is the location of the plain text file cache
+	// used by older versions of Ark.
+	LegacyPath string = ".ark/added_files"
+	// indexVersion is the current version of the index format.
+	indexVersion int = 1
+)
+
+// Index records the files staged for a submission.
+type Index struct {
+	Version int               `json:"version"`
+	Entries map[string]*Entry `json:"entries"`
+}
+
+// Entry records the state of a staged file when it was last hashed.
+type Entry struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mtime"`
+	CID     string    `json:"cid,omitempty"`
+	Type    string    `json:"type,omitempty"`
+}
+
+// Read loads the staging index from the current Ark repository,
+// migrating the legacy plain text file cache if it still exists.
+func Read() (*Index, error) {
+	index := &Index{
+		Version: indexVersion,
+		Entries: make(map[string]*Entry),
+	}
+
+	buf, err := os.ReadFile(IndexPath)
+	if err == nil {
+		err = json.Unmarshal(buf, index)
+		if err != nil {
+			return nil, err
+		}
+		if index.Entries == nil {
+			index.Entries = make(map[string]*Entry)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	err = index.migrate()
+	return index, err
+}
+
+// migrate imports the paths from the legacy file cache. Their
+// CIDs are left empty so they're hashed the next time they're used.
+func (i *Index) migrate() error {
+	f, err := os.Open(LegacyPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		path := scanner.Text()
+		if len(path) == 0 || i.Entries[path] != nil {
+			continue
+		}
+		entry := &Entry{}
+		info, err := os.Stat(path)
+		if err == nil {
+			entry.Size = info.Size()
+			entry.ModTime = info.ModTime()
+		}
+		i.Entries[path] = entry
+	}
+	return scanner.Err()
+}
+
+// Write saves the staging index and removes the legacy file cache.
+// An empty index removes the index file instead.
+func (i *Index) Write() error {
+	err := os.Remove(LegacyPath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	if len(i.Entries) == 0 {
+		err = os.Remove(IndexPath)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	i.Version = indexVersion
+	buf, err := json.MarshalIndent(i, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(IndexPath, append(buf, '\n'), 0644)
+}
+
+// Paths returns the sorted paths of all staged files.
+func (i *Index) Paths() []string {
+	result := make([]string, 0, len(i.Entries))
+	for path := range i.Entries {
+		result = append(result, path)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// Len returns the number of staged files.
+func (i *Index) Len() int {
+	return len(i.Entries)
+}
+
+// Has reports whether a path is staged.
+func (i *Index) Has(path string) bool {
+	_, ok := i.Entries[path]
+	return ok
+}
+
+// Add stages a file or updates its recorded size and modification time,
+// forgetting its CID and type if the file has changed. Add reports whether the
+// file was newly staged.
+func (i *Index) Add(path string, info os.FileInfo) bool {
+	entry, ok := i.Entries[path]
+	if !ok {
+		entry = &Entry{}
+		i.Entries[path] = entry
+	}
+	if entry.Modified(info) {
+		entry.Size = info.Size()
+		entry.ModTime = info.ModTime()
+		entry.CID = ""
+		entry.Type = ""
+	}
+	return !ok
+}
+
+// Remove unstages a file and reports whether it was staged.
+func (i *Index) Remove(path string) bool {
+	_, ok := i.Entries[path]
+	delete(i.Entries, path)
+	return ok
+}
+
+// Size returns the total recorded size of all staged files.
+func (i *Index) Size() (total int64) {
+	for _, entry := range i.Entries {
+		total += entry.Size
+	}
+	return total
+}
+
+// Modified reports whether a file's size or modification time differ
+// from what was recorded when it was staged.
+func (e *Entry) Modified(info os.FileInfo) bool {
+	return e.Size != info.Size() || !e.ModTime.Equal(info.ModTime())
+}
@@ -0,0 +1,145 @@
+package stage
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// chdirRepo changes into a new Ark repository for the rest of a test.
+func chdirRepo(t *testing.T) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Chdir(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	err = os.Mkdir(".ark", 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writeFile creates a file with contents and a fixed modification time.
+func writeFile(t *testing.T, path, contents string, modTime time.Time) os.FileInfo {
+	t.Helper()
+	err := os.WriteFile(path, []byte(contents), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Chtimes(path, modTime, modTime)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info
+}
+
+func TestIndexRoundTrip(t *testing.T) {
+	chdirRepo(t)
+	modTime := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	index, err := Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if index.Len() != 0 {
+		t.Fatalf("a new repository has %d staged file(s)", index.Len())
+	}
+
+	a := writeFile(t, "a.txt", "hello\n", modTime)
+	b := writeFile(t, "b.txt", "hi\n", modTime)
+	if !index.Add("b.txt", b) || !index.Add("a.txt", a) {
+		t.Error("Add didn't report newly staged files")
+	}
+	if index.Add("a.txt", a) {
+		t.Error("Add reported an already staged file as new")
+	}
+	index.Entries["a.txt"].CID = "QmT78zSuBmuS4z925WZfrqQ1qHaJ56DQaTfyMUF7F8ff5o"
+	index.Entries["a.txt"].Type = "text/plain"
+	err = index.Write()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	read, err := Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read.Paths(), []string{"a.txt", "b.txt"}) {
+		t.Errorf("Paths = %v", read.Paths())
+	}
+	want := Entry{Size: 6, ModTime: modTime, CID: "QmT78zSuBmuS4z925WZfrqQ1qHaJ56DQaTfyMUF7F8ff5o", Type: "text/plain"}
+	if got := *read.Entries["a.txt"]; !got.ModTime.Equal(want.ModTime) || got.Size != want.Size ||
+		got.CID != want.CID || got.Type != want.Type {
+		t.Errorf("a.txt = %+v, want %+v", got, want)
+	}
+	if read.Size() != 9 {
+		t.Errorf("Size = %d, want 9", read.Size())
+	}
+
+	// Changing a file forgets its CID so it's hashed again.
+	a = writeFile(t, "a.txt", "hello again\n", modTime.Add(time.Hour))
+	if !read.Entries["a.txt"].Modified(a) {
+		t.Error("a changed file wasn't reported as modified")
+	}
+	read.Add("a.txt", a)
+	if entry := read.Entries["a.txt"]; entry.CID != "" || entry.Type != "" || entry.Size != 12 {
+		t.Errorf("a.txt after changing = %+v", entry)
+	}
+
+	// Removing every file removes the index.
+	if !read.Remove("a.txt") || !read.Remove("b.txt") || read.Remove("b.txt") {
+		t.Error("Remove didn't report which files were staged")
+	}
+	err = read.Write()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(IndexPath); !os.IsNotExist(err) {
+		t.Errorf("the empty index wasn't removed: %v", err)
+	}
+}
+
+func TestIndexMigrate(t *testing.T) {
+	chdirRepo(t)
+	modTime := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	writeFile(t, "a.txt", "hello\n", modTime)
+
+	err := os.WriteFile(LegacyPath, []byte("a.txt\n\n"+filepath.Join("docs", "gone.txt")+"\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	index, err := Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(index.Paths(), []string{"a.txt", filepath.Join("docs", "gone.txt")}) {
+		t.Errorf("Paths = %v", index.Paths())
+	}
+	if entry := index.Entries["a.txt"]; entry.Size != 6 || !entry.ModTime.Equal(modTime) || entry.CID != "" {
+		t.Errorf("migrated a.txt = %+v", entry)
+	}
+
+	// Writing the index removes the legacy file cache.
+	err = index.Write()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(LegacyPath); !os.IsNotExist(err) {
+		t.Errorf("the legacy file cache wasn't removed: %v", err)
+	}
+	if _, err := os.Stat(IndexPath); err != nil {
+		t.Errorf("the index wasn't written: %v", err)
+	}
+}
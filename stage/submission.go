@@ -0,0 +1,79 @@
+package stage
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+)
+
+// SubmissionsPath is the location of the record of submissions
+// made from an Ark repository.
+const SubmissionsPath string = ".ark/submissions.json"
+
+// StatePushed is the state of a submission pushed directly to a
+// manifest, which has no pull request.
+const StatePushed = "pushed"
+
+// Submission records a keyset submitted to a manifest, along with the
+// pull request it was submitted with and its state when last checked.
+// Submissions pushed directly to a manifest have no pull request.
+type Submission struct {
+	Manifest string    `json:"manifest"`
+	Keyset   string    `json:"keyset"`
+	Title    string    `json:"title"`
+	Branch   string    `json:"branch,omitempty"`
+	Number   int       `json:"number,omitempty"`
+	URL      string    `json:"url,omitempty"`
+	State    string    `json:"state"`
+	Created  time.Time `json:"created"`
+	Checked  time.Time `json:"checked"`
+}
+
+// ReadSubmissions loads the record of submissions from the
+// current Ark repository, oldest first.
+func ReadSubmissions() ([]Submission, error) {
+	result := []Submission{}
+	buf, err := os.ReadFile(SubmissionsPath)
+	if os.IsNotExist(err) {
+		return result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(buf, &result)
+	return result, err
+}
+
+// WriteSubmissions saves the record of submissions.
+func WriteSubmissions(submissions []Submission) error {
+	buf, err := json.MarshalIndent(submissions, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(SubmissionsPath, append(buf, '\n'), 0644)
+}
+
+// RecordSubmission adds a submission to the record, replacing any
+// previous submission of the same keyset to the same manifest on the
+// same branch, so retracting from a keyset doesn't hide its submission.
+func RecordSubmission(submission Submission) error {
+	submissions, err := ReadSubmissions()
+	if err != nil {
+		return err
+	}
+
+	replaced := false
+	for i, prev := range submissions {
+		if prev.Manifest == submission.Manifest && prev.Keyset == submission.Keyset &&
+			prev.Branch == submission.Branch {
+			// Keep when the keyset was first submitted.
+			submission.Created = prev.Created
+			submissions[i] = submission
+			replaced = true
+		}
+	}
+	if !replaced {
+		submissions = append(submissions, submission)
+	}
+	return WriteSubmissions(submissions)
+}
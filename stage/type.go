@@ -0,0 +1,31 @@
+package stage
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// DetectType guesses the MIME type of a file from its extension,
+// falling back to sniffing the start of its contents.
+func DetectType(path string) (string, error) {
+	result := mime.TypeByExtension(filepath.Ext(path))
+	if result != "" {
+		return result, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	buf := make([]byte, 512)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	return http.DetectContentType(buf[:n]), nil
+}